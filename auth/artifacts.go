@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	chrome "github.com/chromedp/chromedp"
+)
+
+const (
+	artifactsKeep    = 10 // number of failure artifact directories to keep
+	artifactsPrefix  = "login-"
+	artifactsTimeout = 15 * time.Second
+)
+
+// stepStartBrowser is the step name reported before the first login step
+// has started.
+const stepStartBrowser = "starting browser"
+
+// trace records the executed login steps.
+type trace struct {
+	mu    sync.Mutex
+	steps []traceStep
+}
+
+type traceStep struct {
+	name     string
+	start    time.Time
+	duration time.Duration
+	done     bool
+	err      error
+}
+
+// begin records the start of a step, and returns its index for end.
+func (t *trace) begin(name string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps = append(t.steps, traceStep{name: name, start: time.Now()})
+	return len(t.steps) - 1
+}
+
+func (t *trace) end(i int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &t.steps[i]
+	s.duration = time.Since(s.start)
+	s.done = true
+	s.err = err
+}
+
+// current returns the name of the most recently started step.
+func (t *trace) current() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.steps) == 0 {
+		return stepStartBrowser
+	}
+	return t.steps[len(t.steps)-1].name
+}
+
+func (t *trace) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var sb strings.Builder
+	for _, s := range t.steps {
+		status := "ok"
+		switch {
+		case !s.done:
+			status = "aborted"
+		case s.err != nil:
+			status = "failed: " + s.err.Error()
+		}
+		fmt.Fprintf(&sb, "%s %8.3fs %s (%s)\n", s.start.Format(time.RFC3339Nano), s.duration.Seconds(), s.name, status)
+	}
+	return sb.String()
+}
+
+// saveArtifacts stores a screenshot, the page's HTML and the executed
+// steps in a new subdirectory of artifactsDir, and removes old
+// subdirectories. It returns the new directory, even if some artifacts
+// could not be captured.
+func saveArtifacts(ctx context.Context, tr *trace, le *LoginError) (string, error) {
+	dir := filepath.Join(artifactsDir, artifactsPrefix+time.Now().Format("20060102-150405.000"))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+	defer rotateArtifacts()
+
+	var (
+		screenshot []byte
+		html       string
+	)
+	captureErr := chrome.Run(ctx,
+		chrome.FullScreenshot(&screenshot, 100), // quality 100 yields PNG
+		chrome.OuterHTML("html", &html, chrome.ByQuery),
+	)
+
+	summary := fmt.Sprintf("error: %v\nurl:   %s\ntitle: %s\n\n%s", le.Err, le.URL, le.Title, tr)
+	if captureErr != nil {
+		summary += fmt.Sprintf("\ncapturing page failed: %v\n", captureErr)
+	}
+
+	files := map[string][]byte{
+		"steps.txt": []byte(summary),
+	}
+	if len(screenshot) > 0 {
+		files["screenshot.png"] = screenshot
+	}
+	if html != "" {
+		files["page.html"] = []byte(html)
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			return dir, err
+		}
+	}
+	return dir, nil
+}
+
+// rotateArtifacts removes all but the artifactsKeep most recent artifact
+// directories.
+func rotateArtifacts() {
+	entries, err := os.ReadDir(artifactsDir)
+	if err != nil {
+		return
+	}
+
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), artifactsPrefix) {
+			dirs = append(dirs, e.Name())
+		}
+	}
+	if len(dirs) <= artifactsKeep {
+		return
+	}
+
+	sort.Strings(dirs) // names contain the timestamp
+	for _, name := range dirs[:len(dirs)-artifactsKeep] {
+		_ = os.RemoveAll(filepath.Join(artifactsDir, name))
+	}
+}
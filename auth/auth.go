@@ -15,6 +15,8 @@ var (
 	execPath     chrome.ExecAllocatorOption
 	headless     chrome.ExecAllocatorOption
 	loginTimeout = 5 * time.Minute
+	artifactsDir string
+	remoteURL    string
 )
 
 // SetExecPath sets the path to the Chromium or Google Chrome binary.
@@ -22,6 +24,13 @@ func SetExecPath(path string) {
 	execPath = chrome.ExecPath(path)
 }
 
+// SetRemoteURL configures the login to use an already running browser,
+// instead of starting a new one. The url points to the DevTools endpoint,
+// e.g. "ws://127.0.0.1:9222/" or "http://127.0.0.1:9222/".
+func SetRemoteURL(url string) {
+	remoteURL = url
+}
+
 func SetHeadless(startHeadless bool) {
 	headless = chrome.Flag("headless", startHeadless)
 }
@@ -30,9 +39,62 @@ func SetLoginTimeout(timeout time.Duration) {
 	loginTimeout = timeout
 }
 
+// SetArtifactsDir enables saving a screenshot, the page's HTML and the
+// executed steps into a subdirectory of dir, whenever a login fails.
+func SetArtifactsDir(dir string) {
+	artifactsDir = dir
+}
+
 type AuthInfo struct {
 	SessionID string
 	XSRFToken string
+	LoginAt   time.Time
+}
+
+// DefaultInstance is the landing page of cnMaestro Cloud. It is used
+// when Browser.Instance is empty.
+const DefaultInstance = "https://cloud.cambiumnetworks.com/"
+
+// Browser authenticates by remote-controlling a Chromium or Google Chrome
+// instance through the SSO login.
+type Browser struct {
+	// Instance is the base URL of the cloud instance or on-premises
+	// controller.
+	Instance string
+
+	// LoginURL optionally points directly to the SSO login form. If
+	// empty, the browser navigates to Instance and follows the sign-in
+	// link from there.
+	LoginURL string
+
+	Username string
+	Password string
+
+	// TOTPSecret is the base32-encoded secret for time-based one-time
+	// passwords. If set, the login answers an MFA challenge with a
+	// generated code.
+	TOTPSecret string
+
+	// Steps optionally replaces the built-in login procedure, see
+	// DefaultSteps.
+	Steps []Step
+
+	Verbose bool
+}
+
+// Login performs a browser login at cnMaestro Cloud.
+//
+// Deprecated: Use Browser, which supports other instances, MFA and custom
+// login steps, and reports the session expiry.
+func Login(username, password string, verbose bool) (*AuthInfo, error) {
+	b := &Browser{Username: username, Password: password, Verbose: verbose}
+	info, _, err := b.Login(context.Background())
+	return info, err
+}
+
+// Authenticate performs a browser login.
+func (b *Browser) Authenticate(ctx context.Context) (*AuthInfo, time.Time, error) {
+	return b.Login(ctx)
 }
 
 func wait(dur time.Duration) chrome.ActionFunc {
@@ -48,27 +110,20 @@ const (
 	typingJitter = 30 // also ms
 )
 
-func simulateTyping(sel interface{}, text string, verbose bool, actionName string) []chrome.Action {
-	actions := make([]chrome.Action, 0, 2*len(text)+1)
-	if verbose {
-		actions = append(actions, &actionLogger{
-			log:    true,
-			name:   actionName,
-			Action: chrome.ActionFunc(func(ctx context.Context) error { return nil }),
-		})
-	}
-
+func simulateTyping(sel interface{}, text string, opts ...chrome.QueryOption) chrome.Tasks {
+	actions := make(chrome.Tasks, 0, 2*len(text))
 	for _, r := range text {
 		jitter := time.Duration(rand.Intn(typingJitter)) * time.Millisecond
-		actions = append(actions, chrome.SendKeys(sel, string(r)), wait(typingDelay+jitter))
+		actions = append(actions, chrome.SendKeys(sel, string(r), opts...), wait(typingDelay+jitter))
 	}
 
 	return actions
 }
 
 type actionLogger struct {
-	log  bool
-	name string
+	log   bool
+	name  string
+	trace *trace // optional
 	chrome.Action
 }
 
@@ -76,15 +131,86 @@ func (a *actionLogger) Do(ctx context.Context) error {
 	if a.log {
 		log.Println("<login>", a.name)
 	}
-	return a.Action.Do(ctx)
+	if a.trace == nil {
+		return a.Action.Do(ctx)
+	}
+
+	step := a.trace.begin(a.name)
+	err := a.Action.Do(ctx)
+	a.trace.end(step, err)
+	return err
 }
 
-const loginAnimationTimeout = 5 * time.Second
+// Login performs the login dance in a browser, and returns the session
+// cookies and their expiry.
+func (b *Browser) Login(ctx context.Context) (*AuthInfo, time.Time, error) {
+	var totpKey []byte
+	if b.TOTPSecret != "" {
+		key, err := decodeTOTPSecret(b.TOTPSecret)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		totpKey = key
+	}
 
-func Login(username, password string, verbose bool) (*AuthInfo, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+	// leave some time to collect artifacts after running into loginTimeout
+	ctx, cancel := context.WithTimeout(ctx, loginTimeout+artifactsTimeout)
 	defer cancel()
 
+	allocCtx, aCancel := newAllocator(ctx)
+	defer aCancel()
+
+	taskCtx, tCancel := chrome.NewContext(allocCtx, chrome.WithLogf(log.Printf))
+	defer tCancel()
+
+	tr := &trace{}
+	if remoteURL != "" {
+		// The remote browser might be shared. Connect first, and then use
+		// a separate browser context, to isolate our cookies.
+		if err := chrome.Run(taskCtx); err != nil {
+			return nil, time.Time{}, newLoginError(taskCtx, tr, err)
+		}
+		var iCancel context.CancelFunc
+		taskCtx, iCancel = chrome.NewContext(taskCtx, chrome.WithNewBrowserContext())
+		defer iCancel()
+	}
+
+	run := &loginRun{Browser: b, totpKey: totpKey, trace: tr}
+	steps := b.Steps
+	if steps == nil {
+		steps = DefaultSteps(b.LoginURL != "", totpKey != nil)
+	}
+	actions, err := run.actions(steps)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	// Start the browser before applying loginTimeout. A deadline on the
+	// first run would tear down the browser, before we could collect
+	// failure artifacts.
+	if err := chrome.Run(taskCtx); err != nil {
+		return nil, time.Time{}, newLoginError(taskCtx, tr, err)
+	}
+
+	runCtx, rCancel := context.WithTimeout(taskCtx, loginTimeout)
+	defer rCancel()
+	if err := chrome.Run(runCtx, actions...); err != nil {
+		return nil, time.Time{}, newLoginError(taskCtx, tr, run.classify(err))
+	}
+	if run.info.SessionID == "" {
+		return nil, time.Time{}, newLoginError(taskCtx, tr, fmt.Errorf("%w: no session cookie", ErrUnexpectedPage))
+	}
+	run.info.LoginAt = time.Now()
+	return &run.info, run.expires, nil
+}
+
+// newAllocator returns an allocator for a remote browser, if configured
+// with SetRemoteURL. Otherwise, a new browser process is started.
+func newAllocator(ctx context.Context) (context.Context, context.CancelFunc) {
+	if remoteURL != "" {
+		return chrome.NewRemoteAllocator(ctx, remoteURL)
+	}
+
 	opts := append(chrome.DefaultExecAllocatorOptions[:],
 		chrome.DisableGPU,
 	)
@@ -94,63 +220,54 @@ func Login(username, password string, verbose bool) (*AuthInfo, error) {
 	if headless != nil {
 		opts = append(opts, headless)
 	}
-	allocCtx, aCancel := chrome.NewExecAllocator(ctx, opts...)
-	defer aCancel()
-
-	taskCtx, tCancel := chrome.NewContext(allocCtx, chrome.WithLogf(log.Printf))
-	defer tCancel()
+	return chrome.NewExecAllocator(ctx, opts...)
+}
 
-	withLog := func(name string, action chrome.Action) chrome.Action {
-		return &actionLogger{log: verbose, name: name, Action: action}
+// newLoginError wraps err into a *LoginError, and tries to attach the
+// current page's URL and title. If enabled, failure artifacts are saved.
+// Both are skipped, if the browser did not start.
+func newLoginError(ctx context.Context, tr *trace, err error) *LoginError {
+	le := &LoginError{Step: tr.current(), Err: err}
+	if ctx.Err() != nil || le.Step == stepStartBrowser {
+		return le // browser is gone, or never started
 	}
 
-	info := AuthInfo{}
-	actions := []chrome.Action{
-		withLog("navigate to cloud.cambiumnetworks.com",
-			chrome.Navigate("https://cloud.cambiumnetworks.com/")),
-		withLog("waiting for page to load",
-			chrome.WaitVisible(`form.signin`)),
-		withLog("navigate to SSO login",
-			chrome.Click(`form.signin a.btn-primary`, chrome.NodeVisible)),
-		withLog("waiting for page to load",
-			chrome.WaitVisible(`input[name="email"`)),
-	}
-	actions = append(actions, simulateTyping(`input[name="email"`, username, verbose, "entering email")...)
-	actions = append(actions,
-		withLog("navigate to next page",
-			chrome.Click(`button[name="next"]`)),
-		withLog("waiting for page to load",
-			chrome.WaitVisible(`input[name="password"]`)),
-	)
-	actions = append(actions, simulateTyping(`input[name="password"]`, password, verbose, "entering password")...)
-	actions = append(actions,
-		withLog("ticking 'remember me' checkbox",
-			chrome.Click(`input[name="remember"]`)),
-		withLog("logging in",
-			chrome.Click(`button[name="submit"]`)),
-		withLog("waiting for page to finish animation",
-			wait(loginAnimationTimeout)),
-		withLog("extracting session cookie",
-			extractCookies(&info)),
+	ctx, cancel := context.WithTimeout(ctx, artifactsTimeout)
+	defer cancel()
+	_ = chrome.Run(ctx,
+		chrome.Location(&le.URL),
+		chrome.Title(&le.Title),
 	)
 
-	if err := chrome.Run(taskCtx, actions...); err != nil {
-		return nil, fmt.Errorf("failed to login: %w", err)
+	if artifactsDir != "" {
+		dir, aerr := saveArtifacts(ctx, tr, le)
+		if aerr != nil {
+			log.Printf("saving login artifacts failed: %v", aerr)
+		}
+		le.Artifacts = dir
 	}
-	return &info, nil
+	return le
 }
 
-func extractCookies(info *AuthInfo) chrome.Action {
+// extractCookies copies the session cookies into info. If the session
+// cookie is persistent, its expiry is stored in expires.
+func extractCookies(info *AuthInfo, expires *time.Time) chrome.Action {
 	return chrome.ActionFunc(func(ctx context.Context) error {
 		cookies, err := storage.GetCookies().Do(ctx)
 		if err != nil {
 			return err
 		}
+		if _, err := pageState(ctx, ""); err != nil {
+			return err
+		}
 
 		for _, cookie := range cookies {
 			switch cookie.Name {
 			case "sid":
 				info.SessionID = cookie.Value
+				if !cookie.Session && cookie.Expires > 0 {
+					*expires = time.Unix(int64(cookie.Expires), 0)
+				}
 			case "XSRF-TOKEN":
 				info.XSRFToken = cookie.Value
 			}
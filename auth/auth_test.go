@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoginBrowserStartFailure(t *testing.T) {
+	prevExec, prevDir := execPath, artifactsDir
+	t.Cleanup(func() { execPath, artifactsDir = prevExec, prevDir })
+	SetExecPath(filepath.Join(t.TempDir(), "no-such-browser"))
+	SetArtifactsDir(t.TempDir())
+
+	b := &Browser{Username: "user", Password: "secret"}
+	_, _, err := b.Login(context.Background())
+
+	var le *LoginError
+	if !errors.As(err, &le) {
+		t.Fatalf("expected *LoginError, got %v", err)
+	}
+	if le.Step != stepStartBrowser {
+		t.Errorf("expected failure while %s, got %q", stepStartBrowser, le.Step)
+	}
+	if le.Artifacts != "" || le.URL != "" {
+		t.Errorf("expected no page capture, got artifacts %q, URL %q", le.Artifacts, le.URL)
+	}
+	if entries, _ := os.ReadDir(artifactsDir); len(entries) != 0 {
+		t.Errorf("expected no artifacts, found %d entries", len(entries))
+	}
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator obtains session credentials for the controller's web API.
+//
+// Implementations return the credentials together with their expiry. A
+// zero expiry means the lifetime is unknown, and the caller should fall
+// back to its own refresh schedule.
+type Authenticator interface {
+	Authenticate(ctx context.Context) (info *AuthInfo, expires time.Time, err error)
+}
+
+// AuthenticatorFunc is an adapter to allow the use of ordinary functions
+// as Authenticator.
+type AuthenticatorFunc func(ctx context.Context) (*AuthInfo, time.Time, error)
+
+// Authenticate calls f(ctx).
+func (f AuthenticatorFunc) Authenticate(ctx context.Context) (*AuthInfo, time.Time, error) {
+	return f(ctx)
+}
+
+var (
+	_ Authenticator = AuthenticatorFunc(nil)
+	_ Authenticator = (*Browser)(nil)
+)
+
+// Config holds the login settings from the exporter's configuration
+// file. Authenticators use the parts relevant to them.
+type Config struct {
+	Instance   string
+	LoginURL   string
+	Username   string
+	Password   string
+	TOTPSecret string
+	LoginSteps string // file name, see LoadSteps
+	Verbose    bool
+}
+
+// Factory creates an Authenticator from the configuration.
+type Factory func(cfg *Config) (Authenticator, error)
+
+// DefaultMethod is the login method used, if none is configured.
+const DefaultMethod = "browser"
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{
+		DefaultMethod: newBrowser,
+	}
+)
+
+// Register makes a login method available by name, e.g. for the
+// LoginMethod config entry. It panics, if name is already registered.
+func Register(name string, f Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if _, dup := factories[name]; dup {
+		panic("auth: Register called twice for login method " + name)
+	}
+	factories[name] = f
+}
+
+// New creates an Authenticator for the login method registered as name.
+// An empty name selects DefaultMethod.
+func New(name string, cfg *Config) (Authenticator, error) {
+	if name == "" {
+		name = DefaultMethod
+	}
+
+	factoriesMu.RLock()
+	f, ok := factories[name]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown login method %q (available: %s)", name, strings.Join(methods(), ", "))
+	}
+	return f(cfg)
+}
+
+func methods() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newBrowser creates a Browser, and loads its login steps.
+func newBrowser(cfg *Config) (Authenticator, error) {
+	b := &Browser{
+		Instance:   cfg.Instance,
+		LoginURL:   cfg.LoginURL,
+		Username:   cfg.Username,
+		Password:   cfg.Password,
+		TOTPSecret: cfg.TOTPSecret,
+		Verbose:    cfg.Verbose,
+	}
+	if cfg.LoginSteps != "" {
+		steps, err := LoadSteps(cfg.LoginSteps)
+		if err != nil {
+			return nil, err
+		}
+		b.Steps = steps
+	}
+	return b, nil
+}
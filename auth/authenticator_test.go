@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+var registerTest sync.Once
+
+func TestNew(t *testing.T) {
+	cfg := &Config{
+		Instance: "https://example.com/",
+		Username: "user@example.com",
+		Password: "secret",
+	}
+
+	a, err := New("", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, ok := a.(*Browser)
+	if !ok {
+		t.Fatalf("expected default method to return a *Browser, got %T", a)
+	}
+	if b.Instance != cfg.Instance || b.Username != cfg.Username || b.Password != cfg.Password {
+		t.Errorf("browser does not match config: %+v", b)
+	}
+
+	if _, err := New("carrier-pigeon", cfg); err == nil {
+		t.Error("expected error for unknown login method")
+	}
+	if _, err := New(DefaultMethod, &Config{LoginSteps: "does-not-exist.toml"}); err == nil {
+		t.Error("expected error for missing login steps file")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	static := AuthenticatorFunc(func(context.Context) (*AuthInfo, time.Time, error) {
+		return &AuthInfo{SessionID: "sid"}, time.Time{}, nil
+	})
+	registerTest.Do(func() {
+		Register("static", func(cfg *Config) (Authenticator, error) {
+			return static, nil
+		})
+	})
+
+	a, err := New("static", &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _, err := a.Authenticate(context.Background())
+	if err != nil || info.SessionID != "sid" {
+		t.Errorf("unexpected result from registered authenticator: %+v, %v", info, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected duplicate registration to panic")
+		}
+	}()
+	Register(DefaultMethod, nil)
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	chrome "github.com/chromedp/chromedp"
+)
+
+// Errors returned (wrapped in a *LoginError) when the SSO rejects the
+// login.
+var (
+	ErrBadCredentials = errors.New("bad credentials")
+	ErrInvalidCode    = errors.New("one-time code rejected")
+	ErrAccountLocked  = errors.New("account locked")
+	ErrCaptcha        = errors.New("captcha required")
+	ErrUnexpectedPage = errors.New("unexpected page")
+)
+
+// LoginError describes a failed browser login.
+type LoginError struct {
+	Step  string // name of the failed step
+	URL   string // page URL at the time of failure, if known
+	Title string // page title at the time of failure, if known
+	Err   error
+
+	// Artifacts is the directory containing the failure artifacts, if
+	// enabled with SetArtifactsDir.
+	Artifacts string
+}
+
+func (e *LoginError) Error() string {
+	msg := fmt.Sprintf("login failed while %s: %v", e.Step, e.Err)
+	if e.URL != "" {
+		msg += fmt.Sprintf(" (page %q at %s)", e.Title, e.URL)
+	}
+	return msg
+}
+
+func (e *LoginError) Unwrap() error {
+	return e.Err
+}
+
+// Unrecoverable reports whether err is a login failure, which will not go
+// away by retrying. Retrying might even get the account locked. A rejected
+// one-time code (ErrInvalidCode) is not, as the next code might pass.
+func Unrecoverable(err error) bool {
+	return errors.Is(err, ErrBadCredentials) || errors.Is(err, ErrAccountLocked)
+}
+
+const (
+	stepTimeout  = time.Minute            // default time to wait for a page element
+	pollInterval = 250 * time.Millisecond // how often to inspect the page
+)
+
+// pageStateJS inspects the current page. It returns "visible", once the
+// element given by selector (%s) is visible, or a failure indication, if
+// the page shows a known error. Otherwise it returns an empty string.
+const pageStateJS = `(function(selector) {
+	const visible = (el) => !!(el && (el.offsetWidth || el.offsetHeight || el.getClientRects().length));
+
+	if (selector && visible(document.querySelector(selector))) {
+		return "visible";
+	}
+
+	const captcha = 'iframe[src*="recaptcha"], iframe[src*="hcaptcha"], .g-recaptcha, .h-captcha, #captcha';
+	if (Array.from(document.querySelectorAll(captcha)).some(visible)) {
+		return "captcha";
+	}
+
+	const alerts = '.alert-danger, .alert-error, .error-message, .login-error, [role="alert"]';
+	const text = Array.from(document.querySelectorAll(alerts)).filter(visible).
+		map((el) => el.innerText).join(" ").toLowerCase();
+	if (/locked|disabled|suspended|too many/.test(text)) {
+		return "locked";
+	}
+	if (/invalid|incorrect|wrong|does not match|not recognized/.test(text)) {
+		return "credentials";
+	}
+	return "";
+})(%s)`
+
+var pageStateErrors = map[string]error{
+	"captcha":     ErrCaptcha,
+	"locked":      ErrAccountLocked,
+	"credentials": ErrBadCredentials,
+}
+
+// pageState evaluates pageStateJS. Evaluation errors (e.g. while the page
+// navigates) are reported as empty state.
+func pageState(ctx context.Context, selector string) (string, error) {
+	sel, _ := json.Marshal(selector)
+
+	var state string
+	if err := chrome.Evaluate(fmt.Sprintf(pageStateJS, sel), &state).Do(ctx); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", nil
+	}
+	if err := pageStateErrors[state]; err != nil {
+		return state, err
+	}
+	return state, nil
+}
+
+// waitVisible waits until the element given by selector is visible. In
+// contrast to chrome.WaitVisible, it fails early if the page shows a
+// known error, or when the element does not appear within timeout.
+func waitVisible(selector string, timeout time.Duration) chrome.Action {
+	return chrome.ActionFunc(func(ctx context.Context) error {
+		stepCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		t := time.NewTicker(pollInterval)
+		defer t.Stop()
+
+		for {
+			state, err := pageState(stepCtx, selector)
+			if err != nil {
+				if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+					return fmt.Errorf("%w: %s did not appear", ErrUnexpectedPage, selector)
+				}
+				return err
+			}
+			if state == "visible" {
+				return nil
+			}
+
+			select {
+			case <-stepCtx.Done():
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return fmt.Errorf("%w: %s did not appear", ErrUnexpectedPage, selector)
+			case <-t.C:
+			}
+		}
+	})
+}
+
+// settle waits for dur, while watching the page for known errors.
+func settle(dur time.Duration) chrome.Action {
+	return chrome.ActionFunc(func(ctx context.Context) error {
+		deadline := time.NewTimer(dur)
+		defer deadline.Stop()
+
+		t := time.NewTicker(pollInterval)
+		defer t.Stop()
+
+		for {
+			if _, err := pageState(ctx, ""); err != nil {
+				return err
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-deadline.C:
+				return nil
+			case <-t.C:
+			}
+		}
+	})
+}
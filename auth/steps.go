@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	chrome "github.com/chromedp/chromedp"
+	"github.com/pelletier/go-toml"
+)
+
+// Step actions.
+const (
+	ActionNavigate       = "navigate"        // open URL in Value
+	ActionWaitVisible    = "wait-visible"    // wait for Selector to become visible
+	ActionClick          = "click"           // click on Selector
+	ActionType           = "type"            // type Value into Selector
+	ActionSleep          = "sleep"           // wait for duration in Value, watching for errors
+	ActionExtractCookies = "extract-cookies" // read the session cookies
+)
+
+// Step is a single action of the login procedure.
+//
+// Value may contain the placeholders {{instance}}, {{login_url}},
+// {{username}}, {{password}} and {{totp}}, which are replaced when
+// the step is executed.
+type Step struct {
+	Action   string
+	Name     string // description for logs and failure artifacts (optional)
+	Selector string // CSS selector
+	Value    string
+
+	// Optional steps are skipped, if Selector does not become visible
+	// (within Timeout, for wait-visible steps).
+	Optional bool
+
+	// Timeout overrides how long wait-visible steps wait (optional).
+	Timeout string
+}
+
+const (
+	loginAnimationTimeout = 5 * time.Second
+	mfaTimeout            = 15 * time.Second // how long to wait for an MFA challenge
+)
+
+// DefaultSteps returns the built-in login procedure for cnMaestro Cloud.
+// If withLoginURL is false, the procedure navigates to the instance and
+// follows the sign-in link. If withTOTP is true, an MFA challenge is
+// answered, if it appears.
+func DefaultSteps(withLoginURL, withTOTP bool) []Step {
+	var steps []Step
+	if withLoginURL {
+		steps = append(steps,
+			Step{Action: ActionNavigate, Value: "{{login_url}}"},
+		)
+	} else {
+		steps = append(steps,
+			Step{Action: ActionNavigate, Value: "{{instance}}"},
+			Step{Action: ActionWaitVisible, Selector: `form.signin`, Name: "waiting for page to load"},
+			Step{Action: ActionClick, Selector: `form.signin a.btn-primary`, Name: "navigate to SSO login"},
+		)
+	}
+
+	steps = append(steps,
+		Step{Action: ActionWaitVisible, Selector: `input[name="email"]`, Name: "waiting for page to load"},
+		Step{Action: ActionType, Selector: `input[name="email"]`, Value: "{{username}}", Name: "entering email"},
+		Step{Action: ActionClick, Selector: `button[name="next"]`, Name: "navigate to next page"},
+		Step{Action: ActionWaitVisible, Selector: `input[name="password"]`, Name: "waiting for page to load"},
+		Step{Action: ActionType, Selector: `input[name="password"]`, Value: "{{password}}", Name: "entering password"},
+		Step{Action: ActionClick, Selector: `input[name="remember"]`, Name: "ticking 'remember me' checkbox"},
+		Step{Action: ActionClick, Selector: `button[name="submit"]`, Name: "logging in"},
+	)
+
+	if withTOTP {
+		// The submit button is only clicked within the MFA form, so that
+		// nothing is submitted if the challenge did not appear.
+		const (
+			mfaInput  = `input[autocomplete="one-time-code"], input[name="otp"], input[name="code"]`
+			mfaSubmit = `form:has(` + mfaInput + `) button[type="submit"]`
+		)
+		steps = append(steps,
+			Step{Action: ActionWaitVisible, Selector: mfaInput, Optional: true, Timeout: mfaTimeout.String(), Name: "waiting for MFA challenge"},
+			Step{Action: ActionType, Selector: mfaInput, Value: "{{totp}}", Optional: true, Name: "entering one-time code"},
+			Step{Action: ActionClick, Selector: mfaSubmit, Optional: true, Name: "submitting one-time code"},
+		)
+	}
+
+	return append(steps,
+		Step{Action: ActionSleep, Value: loginAnimationTimeout.String(), Name: "waiting for page to finish animation"},
+		Step{Action: ActionExtractCookies, Name: "extracting session cookie"},
+	)
+}
+
+// LoadSteps reads a login procedure from a TOML or JSON file. The file
+// contains a list of steps, e.g. in TOML:
+//
+//	[[Steps]]
+//	Action = "navigate"
+//	Value  = "{{instance}}"
+func LoadSteps(file string) ([]Step, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read login steps: %w", err)
+	}
+
+	var doc struct {
+		Steps []Step
+	}
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&doc)
+	} else {
+		err = toml.NewDecoder(bytes.NewReader(data)).Strict(true).Decode(&doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode login steps from %q: %w", file, err)
+	}
+	if len(doc.Steps) == 0 {
+		return nil, fmt.Errorf("no login steps in %q", file)
+	}
+
+	for i, s := range doc.Steps {
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("invalid login step %d in %q: %w", i+1, file, err)
+		}
+	}
+	return doc.Steps, nil
+}
+
+func (s *Step) validate() error {
+	switch s.Action {
+	case ActionNavigate:
+		if s.Value == "" {
+			return fmt.Errorf("%s: missing URL", s.Action)
+		}
+	case ActionWaitVisible, ActionClick, ActionType:
+		if s.Selector == "" {
+			return fmt.Errorf("%s: missing selector", s.Action)
+		}
+	case ActionSleep:
+		if _, err := time.ParseDuration(s.Value); err != nil {
+			return fmt.Errorf("%s: %w", s.Action, err)
+		}
+	case ActionExtractCookies:
+	default:
+		return fmt.Errorf("unknown action %q", s.Action)
+	}
+
+	if s.Timeout != "" {
+		if _, err := time.ParseDuration(s.Timeout); err != nil {
+			return fmt.Errorf("%s: invalid timeout: %w", s.Action, err)
+		}
+	}
+	return nil
+}
+
+// name returns a description for logs.
+func (s *Step) name() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	if s.Selector != "" {
+		return s.Action + " " + s.Selector
+	}
+	if s.Action == ActionNavigate {
+		return "navigate to " + s.Value
+	}
+	return s.Action
+}
+
+// loginRun holds the state of a single login.
+type loginRun struct {
+	*Browser
+	totpKey     []byte
+	trace       *trace
+	info        AuthInfo
+	expires     time.Time
+	codeEntered bool // whether a one-time code was typed
+}
+
+// classify refines the error of a failed login. The page does not tell
+// rejected passwords and one-time codes apart. After a code was entered,
+// the password has already been accepted, so the code was rejected (e.g.
+// because of clock skew, or because it expired while typing).
+func (r *loginRun) classify(err error) error {
+	if r.codeEntered && errors.Is(err, ErrBadCredentials) {
+		return fmt.Errorf("%w (%v)", ErrInvalidCode, err)
+	}
+	return err
+}
+
+// expand replaces the placeholders in v.
+func (r *loginRun) expand(v string) (string, error) {
+	if strings.Contains(v, "{{totp}}") {
+		if r.totpKey == nil {
+			return "", fmt.Errorf("{{totp}} used, but no TOTP secret configured")
+		}
+		// generate the code as late as possible
+		v = strings.ReplaceAll(v, "{{totp}}", totp(r.totpKey, time.Now()))
+	}
+
+	instance := r.Instance
+	if instance == "" {
+		instance = DefaultInstance
+	}
+	return strings.NewReplacer(
+		"{{instance}}", instance,
+		"{{login_url}}", r.LoginURL,
+		"{{username}}", r.Username,
+		"{{password}}", r.Password,
+	).Replace(v), nil
+}
+
+// actions converts the steps into browser actions.
+func (r *loginRun) actions(steps []Step) ([]chrome.Action, error) {
+	actions := make([]chrome.Action, 0, len(steps))
+	for i := range steps {
+		s := steps[i]
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("invalid login step %d: %w", i+1, err)
+		}
+
+		name := s.name()
+		if s.Action == ActionNavigate && s.Name == "" {
+			if url, err := r.expand(s.Value); err == nil {
+				name = "navigate to " + url
+			}
+		}
+
+		actions = append(actions, &actionLogger{
+			log:    r.Verbose,
+			name:   name,
+			trace:  r.trace,
+			Action: chrome.ActionFunc(func(ctx context.Context) error { return r.do(ctx, &s) }),
+		})
+	}
+	return actions, nil
+}
+
+func (r *loginRun) do(ctx context.Context, s *Step) error {
+	if s.Optional && s.Action != ActionWaitVisible {
+		if state, err := pageState(ctx, s.Selector); err != nil {
+			return err
+		} else if state != "visible" {
+			return nil // skip
+		}
+	}
+
+	switch s.Action {
+	case ActionNavigate:
+		url, err := r.expand(s.Value)
+		if err != nil {
+			return err
+		}
+		return chrome.Navigate(url).Do(ctx)
+
+	case ActionWaitVisible:
+		timeout := stepTimeout
+		if s.Timeout != "" {
+			timeout, _ = time.ParseDuration(s.Timeout)
+		}
+		err := waitVisible(s.Selector, timeout).Do(ctx)
+		if s.Optional && errors.Is(err, ErrUnexpectedPage) {
+			return nil // element did not appear
+		}
+		return err
+
+	case ActionClick:
+		return chrome.Click(s.Selector, chrome.ByQuery, chrome.NodeVisible).Do(ctx)
+
+	case ActionType:
+		text, err := r.expand(s.Value)
+		if err != nil {
+			return err
+		}
+		if err = simulateTyping(s.Selector, text, chrome.ByQuery).Do(ctx); err != nil {
+			return err
+		}
+		if strings.Contains(s.Value, "{{totp}}") {
+			r.codeEntered = true
+		}
+		return nil
+
+	case ActionSleep:
+		dur, _ := time.ParseDuration(s.Value)
+		return settle(dur).Do(ctx)
+
+	case ActionExtractCookies:
+		return extractCookies(&r.info, &r.expires).Do(ctx)
+	}
+	return fmt.Errorf("unknown action %q", s.Action)
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultStepsMFA(t *testing.T) {
+	var wait *Step
+	for _, s := range DefaultSteps(false, true) {
+		switch {
+		case s.Action == ActionWaitVisible && s.Optional:
+			wait = &s
+		case wait != nil && s.Optional:
+			// later MFA steps must only match within the challenge
+			if !strings.Contains(s.Selector, wait.Selector) {
+				t.Errorf("step %q is not scoped to the MFA challenge: %s", s.name(), s.Selector)
+			}
+		}
+	}
+	if wait == nil {
+		t.Fatal("no MFA step found")
+	}
+
+	for _, s := range DefaultSteps(false, false) {
+		if strings.Contains(s.Value, "{{totp}}") {
+			t.Errorf("unexpected MFA step %q without TOTP", s.name())
+		}
+	}
+}
+
+func TestClassifyRejection(t *testing.T) {
+	rejected := fmt.Errorf("%w: page shows an error", ErrBadCredentials)
+
+	r := &loginRun{}
+	if err := r.classify(rejected); !errors.Is(err, ErrBadCredentials) || !Unrecoverable(err) {
+		t.Errorf("expected rejected password to be unrecoverable, got %v", err)
+	}
+
+	r.codeEntered = true
+	err := r.classify(rejected)
+	if !errors.Is(err, ErrInvalidCode) || errors.Is(err, ErrBadCredentials) {
+		t.Errorf("expected rejection after entering the code to be ErrInvalidCode, got %v", err)
+	}
+	if Unrecoverable(err) {
+		t.Errorf("expected %v to be recoverable", err)
+	}
+
+	if err := r.classify(ErrCaptcha); err != ErrCaptcha {
+		t.Errorf("expected other errors to be kept, got %v", err)
+	}
+}
+
+func TestDefaultSteps(t *testing.T) {
+	for _, tc := range []struct {
+		withLoginURL, withTOTP bool
+		first                  string
+	}{
+		{false, false, "{{instance}}"},
+		{true, false, "{{login_url}}"},
+		{false, true, "{{instance}}"},
+		{true, true, "{{login_url}}"},
+	} {
+		steps := DefaultSteps(tc.withLoginURL, tc.withTOTP)
+		for i, s := range steps {
+			if err := s.validate(); err != nil {
+				t.Errorf("DefaultSteps(%v, %v): invalid step %d: %v", tc.withLoginURL, tc.withTOTP, i+1, err)
+			}
+		}
+		if s := steps[0]; s.Action != ActionNavigate || s.Value != tc.first {
+			t.Errorf("DefaultSteps(%v, %v): expected to navigate to %s first, got %+v", tc.withLoginURL, tc.withTOTP, tc.first, s)
+		}
+		if s := steps[len(steps)-1]; s.Action != ActionExtractCookies {
+			t.Errorf("DefaultSteps(%v, %v): expected to extract cookies last, got %+v", tc.withLoginURL, tc.withTOTP, s)
+		}
+	}
+}
+
+func TestStepValidate(t *testing.T) {
+	for _, tc := range []struct {
+		step Step
+		err  string // expected error substring, empty if valid
+	}{
+		{Step{Action: ActionNavigate, Value: "{{instance}}"}, ""},
+		{Step{Action: ActionNavigate}, "missing URL"},
+		{Step{Action: ActionWaitVisible, Selector: "form", Timeout: "5s"}, ""},
+		{Step{Action: ActionWaitVisible}, "missing selector"},
+		{Step{Action: ActionWaitVisible, Selector: "form", Timeout: "soon"}, "invalid timeout"},
+		{Step{Action: ActionClick, Selector: "button"}, ""},
+		{Step{Action: ActionClick}, "missing selector"},
+		{Step{Action: ActionType, Selector: "input", Value: "{{username}}"}, ""},
+		{Step{Action: ActionType, Value: "{{username}}"}, "missing selector"},
+		{Step{Action: ActionSleep, Value: "2s"}, ""},
+		{Step{Action: ActionSleep, Value: "2"}, "missing unit"},
+		{Step{Action: ActionExtractCookies}, ""},
+		{Step{Action: "hover", Selector: "a"}, `unknown action "hover"`},
+		{Step{}, `unknown action ""`},
+	} {
+		err := tc.step.validate()
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("%+v: unexpected error %v", tc.step, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("%+v: expected error containing %q, got %v", tc.step, tc.err, err)
+		}
+	}
+}
+
+func TestLoadSteps(t *testing.T) {
+	for _, tc := range []struct {
+		name, data string
+		steps      int
+		err        string // expected error substring, empty if valid
+	}{
+		{
+			name:  "steps.toml",
+			data:  "[[Steps]]\nAction = \"navigate\"\nValue = \"{{instance}}\"\n\n[[Steps]]\nAction = \"extract-cookies\"\n",
+			steps: 2,
+		}, {
+			name:  "steps.json",
+			data:  `{"Steps": [{"Action": "navigate", "Value": "{{instance}}"}, {"Action": "extract-cookies"}]}`,
+			steps: 2,
+		}, {
+			name:  "steps.JSON",
+			data:  `{"Steps": [{"Action": "extract-cookies"}]}`,
+			steps: 1,
+		}, {
+			name: "mixed-case.toml",
+			data: "[[Steps]]\nAction = \"navigate\"\nValue = \"{{instance}}\"\n\n[[steps]]\nAction = \"extract-cookies\"\n",
+			err:  "undecoded keys",
+		}, {
+			name: "misspelled.toml",
+			data: "[[Steps]]\nAction = \"click\"\nSelector = \"button\"\nOptinal = true\n",
+			err:  "Optinal",
+		}, {
+			name: "misspelled.json",
+			data: `{"Steps": [{"Action": "click", "Selector": "button", "Optinal": true}]}`,
+			err:  "Optinal",
+		}, {
+			name: "empty.toml",
+			err:  "no login steps",
+		}, {
+			name: "invalid.toml",
+			data: "[[Steps]]\nAction = \"extract-cookies\"\n\n[[Steps]]\nAction = \"click\"\n",
+			err:  "invalid login step 2",
+		}, {
+			name: "syntax.json",
+			data: `{"Steps": [`,
+			err:  "failed to decode",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), tc.name)
+			if err := os.WriteFile(file, []byte(tc.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			steps, err := LoadSteps(file)
+			switch {
+			case tc.err == "" && err != nil:
+				t.Errorf("unexpected error %v", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			case len(steps) != tc.steps:
+				t.Errorf("expected %d steps, got %d", tc.steps, len(steps))
+			}
+		})
+	}
+
+	if _, err := LoadSteps(filepath.Join(t.TempDir(), "missing.toml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected missing file error, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238, Appendix B (SHA1). The reference values have 8 digits,
+	// we compare the trailing totpDigits.
+	key := []byte("12345678901234567890")
+	for _, tc := range []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	} {
+		want := tc.code[len(tc.code)-totpDigits:]
+		if got := totp(key, time.Unix(tc.unix, 0)); got != want {
+			t.Errorf("totp at %d: expected %s, got %s", tc.unix, want, got)
+		}
+	}
+}
+
+func TestDecodeTOTPSecret(t *testing.T) {
+	hello := []byte("Hello!\xde\xad\xbe\xef")
+	foo := []byte("foo")
+
+	for _, tc := range []struct {
+		secret string
+		want   []byte
+	}{
+		{"JBSWY3DPEHPK3PXP", hello},
+		{"jbswy3dpehpk3pxp", hello},      // lower case
+		{"JBSW Y3DP EHPK 3PXP", hello},   // grouped, as shown by setup pages
+		{" jbsw y3dp ehpk 3pxp ", hello}, // both
+		{"MZXW6===", foo},                // padded
+		{"MZXW6", foo},                   // missing padding
+		{"mzxw 6", foo},                  // missing padding, spaces and lower case
+		{"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", []byte("12345678901234567890")},
+	} {
+		got, err := decodeTOTPSecret(tc.secret)
+		if err != nil {
+			t.Errorf("decoding %q failed: %v", tc.secret, err)
+		} else if !bytes.Equal(got, tc.want) {
+			t.Errorf("decoding %q: expected %q, got %q", tc.secret, tc.want, got)
+		}
+	}
+
+	for _, secret := range []string{
+		"JBSWY3DPEHPK3PX1", // invalid character
+		"MZ=XW6",           // padding in the middle
+	} {
+		if _, err := decodeTOTPSecret(secret); err == nil {
+			t.Errorf("expected decoding %q to fail", secret)
+		}
+	}
+}
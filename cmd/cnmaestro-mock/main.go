@@ -0,0 +1,35 @@
+// Command cnmaestro-mock runs a mock cnMaestro controller with demo data,
+// for local development of the exporter.
+package main
+
+import (
+	"log"
+	"net"
+
+	"github.com/digineo/cambium-exporter/cnmaestrotest"
+
+	kingpin "github.com/alecthomas/kingpin/v2"
+)
+
+func main() {
+	log.SetFlags(log.Lshortfile)
+
+	listenAddress := kingpin.Flag("web.listen-address", "Address to listen on.").Default("localhost:4567").String()
+	kingpin.HelpFlag.Short('h')
+	kingpin.Parse()
+
+	ln, err := net.Listen("tcp", *listenAddress)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	srv := cnmaestrotest.NewUnstartedServer(cnmaestrotest.DemoFixtures())
+	srv.Listener.Close()
+	srv.Listener = ln
+	srv.Start()
+
+	log.Printf("mock controller listening on %s", srv.URL)
+	log.Printf("login with %q / %q", srv.Username, srv.Password)
+	log.Printf("REST API client credentials: %q / %q", srv.ClientID, srv.ClientSecret)
+	select {}
+}
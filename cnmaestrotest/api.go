@@ -0,0 +1,239 @@
+package cnmaestrotest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type jsonObject = map[string]interface{}
+
+func (s *Server) userMe(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	_, xsrf := s.Credentials()
+	http.SetCookie(w, &http.Cookie{Name: "XSRF-TOKEN", Value: xsrf, Path: "/"})
+
+	writeJSON(w, http.StatusOK, jsonObject{
+		"data": jsonObject{"email": s.Username},
+	})
+}
+
+// profiles serves the AP group list. The web UI filters with a
+// "name:<group>" entry in the fields parameter.
+func (s *Server) profiles(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var filter string
+	for _, f := range strings.Split(r.URL.Query().Get("fields"), ",") {
+		if name, ok := strings.CutPrefix(f, "name:"); ok {
+			filter = name
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profiles := []jsonObject{}
+	for _, g := range s.fixtures.APGroups {
+		if filter != "" && g.Name != filter {
+			continue
+		}
+		offline := 0
+		for _, d := range g.Devices {
+			if !d.Online {
+				offline++
+			}
+		}
+		profiles = append(profiles, jsonObject{
+			"name":           g.Name,
+			"hasDevices":     len(g.Devices) > 0,
+			"deviceCount":    len(g.Devices),
+			"offlineCount":   offline,
+			"outOfSyncCount": g.DevicesOutOfSync,
+			"clientCount":    g.ClientCount,
+			"clientCount24h": g.ClientCount24H,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, jsonObject{
+		"data": jsonObject{"profiles": profiles},
+	})
+}
+
+func (s *Server) devices(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	name := params.ByName("name")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, g := range s.fixtures.APGroups {
+		if g.Name != name {
+			continue
+		}
+
+		from, to := s.page(r, len(g.Devices))
+		devices := make([]jsonObject, 0, to-from)
+		for _, d := range g.Devices[from:to] {
+			devices = append(devices, deviceJSON(&d))
+		}
+		writeJSON(w, http.StatusOK, jsonObject{
+			"data": jsonObject{
+				"profiles": jsonObject{"devices": devices},
+			},
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusNotFound, jsonObject{"message": "AP group not found"})
+}
+
+func deviceJSON(d *Device) jsonObject {
+	sys := jsonObject{
+		"online": d.Online,
+	}
+	if d.Online {
+		sys["upTime"] = d.Since.UnixMilli()
+	} else {
+		sys["dnTime"] = d.Since.UnixMilli()
+	}
+	if !d.LastReboot.IsZero() {
+		sys["lastRbt"] = []jsonObject{{
+			"uTs":  d.LastReboot.Unix(),
+			"code": d.RebootReason,
+		}}
+	}
+
+	radios := make([]jsonObject, 0, len(d.Radios))
+	for _, r := range d.Radios {
+		radio := jsonObject{
+			"id":      r.ID,
+			"mac":     r.MAC,
+			"band":    r.Band,
+			"channel": strconv.Itoa(r.Channel),
+			"chWidth": strconv.Itoa(r.ChannelWidth),
+			"pow":     r.Power,
+			"rfqlt":   r.Quality,
+			"rxAvg":   r.RxKbps,
+			"txAvg":   r.TxKbps,
+		}
+		if h := r.Health; h != nil {
+			radio["noiseFloor"] = h.NoiseFloor
+			radio["chUtil"] = h.ChannelUtil
+			radio["intf"] = h.Interference
+			radio["txRetries"] = h.TxRetries
+			radio["txErr"] = h.TxErrors
+			radio["staCnt"] = h.Stations
+		}
+		radios = append(radios, radio)
+	}
+
+	return jsonObject{
+		"model":  d.Model,
+		"mac":    d.MAC,
+		"sn":     d.Serial,
+		"tid":    d.Site,
+		"cfg":    jsonObject{"name": d.Hostname},
+		"sys":    sys,
+		"mgmt":   jsonObject{"actSw": d.Firmware},
+		"lstUpd": time.Now().UnixMilli(),
+		"radios": radios,
+	}
+}
+
+func (s *Server) clients(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	name := params.ByName("name")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, g := range s.fixtures.APGroups {
+		if g.Name != name {
+			continue
+		}
+
+		var clients []jsonObject
+		for _, d := range g.Devices {
+			bands := make(map[int]string, len(d.Radios))
+			for _, r := range d.Radios {
+				bands[r.ID] = r.Band
+			}
+			for _, c := range d.Clients {
+				clients = append(clients, jsonObject{
+					"mac":   c.MAC,
+					"apMac": d.MAC,
+					"ssid":  c.SSID,
+					"rssi":  c.RSSI,
+					"snr":   c.SNR,
+					"radio": jsonObject{"id": c.RadioID, "band": bands[c.RadioID]},
+				})
+			}
+		}
+
+		from, to := s.page(r, len(clients))
+		writeJSON(w, http.StatusOK, jsonObject{
+			"data": jsonObject{
+				"profiles": jsonObject{"clients": append([]jsonObject{}, clients[from:to]...)},
+			},
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusNotFound, jsonObject{"message": "AP group not found"})
+}
+
+func (s *Server) portals(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, to := s.page(r, len(s.fixtures.Portals))
+	result := make([]jsonObject, 0, to-from)
+	for _, p := range s.fixtures.Portals[from:to] {
+		result = append(result, jsonObject{"name": p.Name})
+	}
+
+	writeJSON(w, http.StatusOK, jsonObject{
+		"data": jsonObject{
+			"_metadata": metadata(from, to, len(s.fixtures.Portals)),
+			"result":    result,
+		},
+	})
+}
+
+func (s *Server) sessions(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	name := params.ByName("name")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.fixtures.Portals {
+		if p.Name != name {
+			continue
+		}
+
+		from, to := s.page(r, len(p.Sessions))
+		result := make([]jsonObject, 0, to-from)
+		for _, sess := range p.Sessions[from:to] {
+			result = append(result, jsonObject{
+				"apMAC":     sess.APMAC,
+				"clientMac": sess.ClientMAC,
+			})
+		}
+		writeJSON(w, http.StatusOK, jsonObject{
+			"data": jsonObject{
+				"_metadata": metadata(from, to, len(p.Sessions)),
+				"result":    result,
+			},
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusNotFound, jsonObject{"message": "portal not found"})
+}
+
+func metadata(from, to, total int) jsonObject {
+	return jsonObject{
+		"limit":      to - from,
+		"offset":     from,
+		"totalCount": total,
+	}
+}
@@ -0,0 +1,152 @@
+package cnmaestrotest
+
+import "time"
+
+// Fixtures define the data served by the mock controller.
+type Fixtures struct {
+	APGroups []APGroup
+	Portals  []Portal
+}
+
+// APGroup is a WiFi AP group.
+type APGroup struct {
+	Name             string
+	DevicesOutOfSync int
+	ClientCount      int
+	ClientCount24H   int
+	Devices          []Device
+}
+
+// Device is a WiFi access point.
+type Device struct {
+	MAC          string
+	Model        string
+	Serial       string
+	Site         string
+	Hostname     string
+	Firmware     string
+	Online       bool
+	Since        time.Time // time of last status change
+	LastReboot   time.Time
+	RebootReason string
+	Radios       []Radio
+	Clients      []Client
+}
+
+// Radio is a radio of a Device.
+type Radio struct {
+	ID           int
+	MAC          string
+	Band         string // e.g. "2.4GHz", "5GHz", "6GHz"
+	Channel      int
+	ChannelWidth int // in MHz
+	Power        int
+	Quality      int // percentage points
+	RxKbps       int
+	TxKbps       int
+	Health       *RadioHealth // optional, not reported by older firmware
+}
+
+// RadioHealth holds the health statistics of a Radio.
+type RadioHealth struct {
+	NoiseFloor   int // in dBm
+	ChannelUtil  int // percentage points
+	Interference int // percentage points
+	TxRetries    int // percentage points
+	TxErrors     int // percentage points
+	Stations     int
+}
+
+// Client is a wireless client connected to a Device.
+type Client struct {
+	MAC     string
+	SSID    string
+	RadioID int // refers to Radio.ID
+	RSSI    int // in dBm
+	SNR     int // in dB
+}
+
+// Portal is a guest access portal.
+type Portal struct {
+	Name     string
+	Sessions []Session
+}
+
+// Session is a guest access session.
+type Session struct {
+	APMAC     string
+	ClientMAC string
+}
+
+// DemoFixtures returns a small, but complete set of fixtures.
+func DemoFixtures() Fixtures {
+	now := time.Now()
+	return Fixtures{
+		APGroups: []APGroup{{
+			Name:           "Default",
+			ClientCount:    5,
+			ClientCount24H: 42,
+			Devices: []Device{{
+				MAC:          "00:04:56:00:00:01",
+				Model:        "XV2-2",
+				Serial:       "W8VA00000001",
+				Site:         "Headquarters",
+				Hostname:     "ap-lobby",
+				Firmware:     "6.4.2",
+				Online:       true,
+				Since:        now.Add(-72 * time.Hour),
+				LastReboot:   now.Add(-72 * time.Hour),
+				RebootReason: "Power On",
+				Radios: []Radio{
+					{ID: 1, MAC: "00:04:56:00:01:01", Band: "2.4GHz", Channel: 6, ChannelWidth: 20, Power: 18, Quality: 92, RxKbps: 120, TxKbps: 900},
+					{ID: 2, MAC: "00:04:56:00:01:02", Band: "5GHz", Channel: 36, ChannelWidth: 80, Power: 21, Quality: 97, RxKbps: 850, TxKbps: 9800},
+				},
+				Clients: []Client{
+					{MAC: "02:00:00:00:00:01", SSID: "Guests", RadioID: 1, RSSI: -71, SNR: 19},
+					{MAC: "02:00:00:00:00:02", SSID: "Guests", RadioID: 2, RSSI: -58, SNR: 33},
+					{MAC: "02:00:00:00:00:03", SSID: "Staff", RadioID: 2, RSSI: -49, SNR: 42},
+				},
+			}, {
+				MAC:      "00:04:56:00:00:02",
+				Model:    "XV2-2",
+				Serial:   "W8VA00000002",
+				Site:     "Headquarters",
+				Hostname: "ap-storage",
+				Firmware: "6.4.2",
+				Since:    now.Add(-2 * time.Hour),
+			}, {
+				MAC:          "00:04:56:00:00:03",
+				Model:        "XE3-4",
+				Serial:       "W8VA00000003",
+				Site:         "Headquarters",
+				Hostname:     "ap-conference",
+				Firmware:     "6.5.1",
+				Online:       true,
+				Since:        now.Add(-24 * time.Hour),
+				LastReboot:   now.Add(-24 * time.Hour),
+				RebootReason: "Firmware Upgrade",
+				Radios: []Radio{
+					{ID: 1, MAC: "00:04:56:00:03:01", Band: "2.4GHz", Channel: 1, ChannelWidth: 20, Power: 16, Quality: 88, RxKbps: 60, TxKbps: 400,
+						Health: &RadioHealth{NoiseFloor: -92, ChannelUtil: 47, Interference: 21, TxRetries: 12, TxErrors: 2, Stations: 0}},
+					{ID: 2, MAC: "00:04:56:00:03:02", Band: "5GHz", Channel: 149, ChannelWidth: 80, Power: 22, Quality: 95, RxKbps: 1200, TxKbps: 14500,
+						Health: &RadioHealth{NoiseFloor: -95, ChannelUtil: 18, Interference: 4, TxRetries: 5, TxErrors: 0, Stations: 1}},
+					{ID: 3, MAC: "00:04:56:00:03:03", Band: "6GHz", Channel: 37, ChannelWidth: 160, Power: 20, Quality: 99, RxKbps: 3100, TxKbps: 42000,
+						Health: &RadioHealth{NoiseFloor: -97, ChannelUtil: 9, Interference: 1, TxRetries: 2, TxErrors: 0, Stations: 1}},
+				},
+				Clients: []Client{
+					{MAC: "02:00:00:00:00:04", SSID: "Staff", RadioID: 3, RSSI: -62, SNR: 31},
+					{MAC: "02:00:00:00:00:05", SSID: "Staff", RadioID: 2, RSSI: -83, SNR: 8},
+				},
+			}},
+		}, {
+			Name: "Empty",
+		}},
+		Portals: []Portal{{
+			Name: "VisitorPortal",
+			Sessions: []Session{
+				{APMAC: "00:04:56:00:00:01", ClientMAC: "02:00:00:00:00:01"},
+				{APMAC: "00:04:56:00:00:01", ClientMAC: "02:00:00:00:00:02"},
+			},
+		}},
+	}
+}
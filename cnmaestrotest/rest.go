@@ -0,0 +1,232 @@
+package cnmaestrotest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Default credentials accepted by the REST API's token endpoint.
+const (
+	DefaultClientID     = "exporter"
+	DefaultClientSecret = "s3cr3t"
+)
+
+// tokenLifetime is reported as expires_in by the token endpoint.
+const tokenLifetime = 3600 // seconds
+
+func (s *Server) restRoutes(router *httprouter.Router) {
+	const prefix = "/api/v2"
+	router.POST(prefix+"/access/token", s.accessToken)
+	router.GET(prefix+"/ap-groups", s.rest(s.restAPGroups))
+	router.GET(prefix+"/devices/statistics", s.rest(s.restDevices))
+	router.GET(prefix+"/devices/clients", s.rest(s.restClients))
+	router.GET(prefix+"/guest-portals", s.rest(s.restPortals))
+	router.GET(prefix+"/guest-portals/:name/sessions", s.rest(s.restSessions))
+}
+
+// AccessToken returns the access token, which is issued by the REST API's
+// token endpoint.
+func (s *Server) AccessToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+// ExpireToken invalidates the current access token. Subsequent REST API
+// requests with the old token are rejected with 401 Unauthorized.
+func (s *Server) ExpireToken() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = randomToken()
+}
+
+// accessToken implements the OAuth2 client credentials grant.
+func (s *Server) accessToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != s.ClientID || secret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, jsonObject{"error": "invalid_client"})
+		return
+	}
+	if r.PostFormValue("grant_type") != "client_credentials" {
+		writeJSON(w, http.StatusBadRequest, jsonObject{"error": "unsupported_grant_type"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, jsonObject{
+		"access_token": s.AccessToken(),
+		"token_type":   "bearer",
+		"expires_in":   tokenLifetime,
+	})
+}
+
+// rest wraps handlers for the REST API, which require a valid access
+// token.
+func (s *Server) rest(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token != s.AccessToken() {
+			writeJSON(w, http.StatusUnauthorized, jsonObject{"error": "invalid_token"})
+			return
+		}
+		h(w, r, params)
+	}
+}
+
+// restList responds with a page of a REST API list.
+func (s *Server) restList(w http.ResponseWriter, r *http.Request, data []jsonObject) {
+	from, to := s.page(r, len(data))
+	writeJSON(w, http.StatusOK, jsonObject{
+		"data": append([]jsonObject{}, data[from:to]...),
+		"paging": jsonObject{
+			"limit":  to - from,
+			"offset": from,
+			"total":  len(data),
+		},
+	})
+}
+
+func (s *Server) restAPGroups(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	groups := make([]jsonObject, 0, len(s.fixtures.APGroups))
+	for _, g := range s.fixtures.APGroups {
+		groups = append(groups, jsonObject{"name": g.Name})
+	}
+	s.restList(w, r, groups)
+}
+
+// group returns the AP group named by the "ap_group" parameter, or nil.
+// The caller must hold s.mu.
+func (s *Server) group(r *http.Request) *APGroup {
+	name := r.URL.Query().Get("ap_group")
+	for i := range s.fixtures.APGroups {
+		if s.fixtures.APGroups[i].Name == name {
+			return &s.fixtures.APGroups[i]
+		}
+	}
+	return nil
+}
+
+func (s *Server) restDevices(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	devices := []jsonObject{}
+	if g := s.group(r); g != nil {
+		for _, d := range g.Devices {
+			devices = append(devices, restDeviceJSON(&d))
+		}
+	}
+	s.restList(w, r, devices)
+}
+
+func restDeviceJSON(d *Device) jsonObject {
+	status := "offline"
+	if d.Online {
+		status = "online"
+	}
+
+	radios := make([]jsonObject, 0, len(d.Radios))
+	for _, r := range d.Radios {
+		radio := jsonObject{
+			"id":            r.ID,
+			"mac":           r.MAC,
+			"band":          r.Band,
+			"channel":       strconv.Itoa(r.Channel),
+			"channel_width": strconv.Itoa(r.ChannelWidth) + " MHz",
+			"tx_power":      r.Power,
+			"rf_quality":    r.Quality,
+			"rx_bps":        r.RxKbps * 1000,
+			"tx_bps":        r.TxKbps * 1000,
+		}
+		if h := r.Health; h != nil {
+			radio["noise_floor"] = h.NoiseFloor
+			radio["channel_utilization"] = h.ChannelUtil
+			radio["interference"] = h.Interference
+			radio["tx_retries"] = h.TxRetries
+			radio["tx_errors"] = h.TxErrors
+			radio["clients"] = h.Stations
+		}
+		radios = append(radios, radio)
+	}
+
+	return jsonObject{
+		"product":            d.Model,
+		"mac":                d.MAC,
+		"msn":                d.Serial,
+		"site":               d.Site,
+		"name":               d.Hostname,
+		"software_version":   d.Firmware,
+		"status":             status,
+		"status_time":        d.Since.Unix(),
+		"last_reboot_reason": d.RebootReason,
+		"connected_clients":  len(d.Clients),
+		"radios":             radios,
+	}
+}
+
+func (s *Server) restClients(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clients := []jsonObject{}
+	if g := s.group(r); g != nil {
+		for _, d := range g.Devices {
+			bands := make(map[int]string, len(d.Radios))
+			for _, r := range d.Radios {
+				bands[r.ID] = r.Band
+			}
+			for _, c := range d.Clients {
+				clients = append(clients, jsonObject{
+					"mac":    c.MAC,
+					"ap_mac": d.MAC,
+					"ssid":   c.SSID,
+					"band":   bands[c.RadioID],
+					"rssi":   c.RSSI,
+					"snr":    c.SNR,
+				})
+			}
+		}
+	}
+	s.restList(w, r, clients)
+}
+
+func (s *Server) restPortals(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	portals := make([]jsonObject, 0, len(s.fixtures.Portals))
+	for _, p := range s.fixtures.Portals {
+		portals = append(portals, jsonObject{"name": p.Name})
+	}
+	s.restList(w, r, portals)
+}
+
+func (s *Server) restSessions(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	name := params.ByName("name")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.fixtures.Portals {
+		if p.Name != name {
+			continue
+		}
+
+		sessions := make([]jsonObject, 0, len(p.Sessions))
+		for _, sess := range p.Sessions {
+			sessions = append(sessions, jsonObject{
+				"ap_mac":     sess.APMAC,
+				"client_mac": sess.ClientMAC,
+			})
+		}
+		s.restList(w, r, sessions)
+		return
+	}
+
+	writeJSON(w, http.StatusNotFound, jsonObject{"error": "portal not found"})
+}
@@ -0,0 +1,229 @@
+// Package cnmaestrotest provides a mock cnMaestro controller for tests
+// and local development.
+//
+// The mock serves the SSO login pages used by the browser login, the
+// internal cn-srv endpoints queried by the exporter, and the parts of the
+// northbound REST API (/api/v2) used instead, if API client credentials are
+// configured. The served data is defined by Fixtures, and errors can be
+// injected per endpoint.
+package cnmaestrotest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Default credentials accepted by the SSO login.
+const (
+	DefaultUsername = "anything@example.com"
+	DefaultPassword = "sup3rs3cr3t"
+)
+
+// Server is a mock cnMaestro controller.
+type Server struct {
+	*httptest.Server
+
+	// Username and Password are the credentials accepted by the SSO login.
+	Username string
+	Password string
+
+	// ClientID and ClientSecret are the REST API client credentials.
+	ClientID     string
+	ClientSecret string
+
+	// MaxPageSize limits the number of list entries per response (0 means
+	// unlimited). This allows to exercise pagination with few fixtures.
+	MaxPageSize int
+
+	mu        sync.Mutex
+	fixtures  Fixtures
+	sessionID string
+	xsrfToken string
+	token     string // REST API access token
+	logins    int
+	requests  map[string]int       // key = request path
+	failures  map[string][]failure // key = request path
+}
+
+type failure struct {
+	status int
+	count  int // remaining responses, < 0 means forever
+}
+
+// NewServer starts a mock controller serving the given fixtures.
+func NewServer(f Fixtures) *Server {
+	s := NewUnstartedServer(f)
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer returns a mock controller, but doesn't start it.
+// The caller should call Start when needed.
+func NewUnstartedServer(f Fixtures) *Server {
+	s := &Server{
+		Username: DefaultUsername,
+		Password: DefaultPassword,
+
+		ClientID:     DefaultClientID,
+		ClientSecret: DefaultClientSecret,
+
+		fixtures: f,
+		requests: make(map[string]int),
+		failures: make(map[string][]failure),
+	}
+	s.sessionID = randomToken()
+	s.xsrfToken = randomToken()
+	s.token = randomToken()
+	s.Server = httptest.NewUnstartedServer(s.routes())
+	return s
+}
+
+func (s *Server) routes() http.Handler {
+	router := httprouter.New()
+
+	router.GET("/", s.landingPage)
+	router.GET("/cn-rtr/sso", s.loginPage)
+	router.POST("/login", s.login)
+	router.GET("/app", s.appPage)
+
+	const prefix = "/0/cn-srv"
+	router.GET(prefix+"/user/me", s.api(s.userMe))
+	router.GET(prefix+"/config/profiles", s.api(s.profiles))
+	router.GET(prefix+"/stats/profiles/:name/devices", s.api(s.devices))
+	router.GET(prefix+"/stats/profiles/:name/clients", s.api(s.clients))
+	router.GET(prefix+"/services/guest/portal", s.api(s.portals))
+	router.GET(prefix+"/services/guest/session/:name", s.api(s.sessions))
+
+	s.restRoutes(router)
+
+	return s.count(router)
+}
+
+// SetFixtures replaces the served data.
+func (s *Server) SetFixtures(f Fixtures) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures = f
+}
+
+// Credentials returns the session cookie values, which are set by the
+// SSO login.
+func (s *Server) Credentials() (sessionID, xsrfToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessionID, s.xsrfToken
+}
+
+// ExpireSession invalidates the current session. Subsequent API requests
+// with the old session cookie are rejected with 401 Unauthorized.
+func (s *Server) ExpireSession() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessionID = randomToken()
+	s.xsrfToken = randomToken()
+}
+
+// InjectError makes the next count requests to path fail with the given
+// HTTP status. A negative count lets all requests fail. The path includes
+// the "/0/cn-srv" prefix for API requests. Multiple injections for the same
+// path are consumed in order.
+func (s *Server) InjectError(path string, status, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = append(s.failures[path], failure{status: status, count: count})
+}
+
+// ClearErrors removes all injected errors.
+func (s *Server) ClearErrors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = make(map[string][]failure)
+}
+
+// Logins returns the number of successful SSO logins, including those
+// through Authenticator.
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// Requests returns the number of requests to path.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// count tracks requests, and responds with injected errors.
+func (s *Server) count(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		status := 0
+		if queue := s.failures[r.URL.Path]; len(queue) > 0 {
+			status = queue[0].status
+			if queue[0].count > 0 {
+				queue[0].count--
+				if queue[0].count == 0 {
+					s.failures[r.URL.Path] = queue[1:]
+				}
+			}
+		}
+		s.mu.Unlock()
+
+		if status != 0 {
+			writeJSON(w, status, map[string]string{"message": http.StatusText(status)})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// api wraps handlers for the cn-srv API, which require a valid session.
+func (s *Server) api(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		sid, _ := s.Credentials()
+		if c, err := r.Cookie("sid"); err != nil || c.Value != sid {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			return
+		}
+		h(w, r, params)
+	}
+}
+
+// page applies limit and offset query parameters, as well as MaxPageSize,
+// to a list of n entries. It returns the bounds of the page.
+func (s *Server) page(r *http.Request, n int) (from, to int) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	if s.MaxPageSize > 0 && (limit <= 0 || limit > s.MaxPageSize) {
+		limit = s.MaxPageSize
+	}
+	from = min(max(offset, 0), n)
+	to = n
+	if limit > 0 {
+		to = min(from+limit, n)
+	}
+	return from, to
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomToken() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,102 @@
+package cnmaestrotest
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/digineo/cambium-exporter/auth"
+	"github.com/julienschmidt/httprouter"
+)
+
+// The pages mimic the structure of the cnMaestro Cloud SSO, as expected
+// by auth.DefaultSteps. The password field is revealed after clicking
+// "next", like on the real SSO.
+var pages = template.Must(template.New("").Parse(`
+{{ define "landing" }}<!doctype html>
+<html>
+<head><meta charset="UTF-8"><title>cnMaestro</title></head>
+<body>
+	<form class="signin">
+		<a class="btn btn-primary" href="/cn-rtr/sso">Sign In</a>
+	</form>
+</body>
+</html>{{ end }}
+
+{{ define "login" }}<!doctype html>
+<html>
+<head><meta charset="UTF-8"><title>SSO Log In</title></head>
+<body>
+	{{ if . }}<div class="alert alert-danger" role="alert">{{ . }}</div>{{ end }}
+	<form id="login" method="POST" action="/login">
+		<input type="email" name="email" placeholder="Email address">
+		<button type="button" name="next">Next</button>
+		<div id="password" style="display:none">
+			<input type="password" name="password" placeholder="Password">
+			<label><input type="checkbox" name="remember" value="yes"> Remember me</label>
+			<button type="submit" name="submit">Sign in</button>
+		</div>
+	</form>
+	<script>
+		document.querySelector('button[name="next"]').addEventListener("click", () => {
+			document.getElementById("password").style.display = "block"
+		})
+	</script>
+</body>
+</html>{{ end }}
+
+{{ define "app" }}<!doctype html>
+<html>
+<head><meta charset="UTF-8"><title>cnMaestro</title></head>
+<body>
+	<h3>Cloud Account Name: Testing Test</h3>
+</body>
+</html>{{ end }}
+`))
+
+func render(w http.ResponseWriter, status int, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_ = pages.ExecuteTemplate(w, name, data)
+}
+
+func (s *Server) landingPage(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	render(w, http.StatusOK, "landing", nil)
+}
+
+func (s *Server) loginPage(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	render(w, http.StatusOK, "login", "")
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if r.PostFormValue("email") != s.Username || r.PostFormValue("password") != s.Password {
+		render(w, http.StatusOK, "login", "Invalid email or password.")
+		return
+	}
+
+	s.mu.Lock()
+	s.logins++
+	sid, xsrf := s.sessionID, s.xsrfToken
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{Name: "sid", Value: sid, Path: "/", HttpOnly: true})
+	http.SetCookie(w, &http.Cookie{Name: "XSRF-TOKEN", Value: xsrf, Path: "/"})
+	http.Redirect(w, r, "/app", http.StatusSeeOther)
+}
+
+func (s *Server) appPage(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	render(w, http.StatusOK, "app", nil)
+}
+
+// Authenticator returns an auth.Authenticator, which logs in without a
+// browser. It acts like a successful SSO login: it is counted by Logins,
+// and returns the current session cookies.
+func (s *Server) Authenticator() auth.Authenticator {
+	return auth.AuthenticatorFunc(func(context.Context) (*auth.AuthInfo, time.Time, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.logins++
+		return &auth.AuthInfo{SessionID: s.sessionID, XSRFToken: s.xsrfToken}, time.Time{}, nil
+	})
+}
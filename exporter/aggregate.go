@@ -0,0 +1,160 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const defaultAggregateConcurrency = 4
+
+// Aggregate configures the /metrics endpoint, which collects all AP
+// groups and portals at once.
+type Aggregate struct {
+	// Include and exclude lists of regular expressions, matched against
+	// the full name. Empty include lists match everything. Exclusion
+	// takes precedence.
+	IncludeAPGroups []string
+	ExcludeAPGroups []string
+	IncludePortals  []string
+	ExcludePortals  []string
+
+	// Concurrency limits the number of AP groups and portals collected
+	// in parallel (default: 4).
+	Concurrency int
+
+	includeAPGroups []*regexp.Regexp
+	excludeAPGroups []*regexp.Regexp
+	includePortals  []*regexp.Regexp
+	excludePortals  []*regexp.Regexp
+}
+
+func (a *Aggregate) compile() (err error) {
+	if a.Concurrency <= 0 {
+		a.Concurrency = defaultAggregateConcurrency
+	}
+
+	for _, x := range []struct {
+		exprs []string
+		dst   *[]*regexp.Regexp
+	}{
+		{a.IncludeAPGroups, &a.includeAPGroups},
+		{a.ExcludeAPGroups, &a.excludeAPGroups},
+		{a.IncludePortals, &a.includePortals},
+		{a.ExcludePortals, &a.excludePortals},
+	} {
+		if *x.dst, err = compileAnchored(x.exprs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func compileAnchored(exprs []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", expr, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func matchAny(res []*regexp.Regexp, name string) bool {
+	for _, re := range res {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the names, which are included and not excluded.
+func filter(names []string, include, exclude []*regexp.Regexp) []string {
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		if len(include) > 0 && !matchAny(include, name) {
+			continue
+		}
+		if matchAny(exclude, name) {
+			continue
+		}
+		result = append(result, name)
+	}
+	return result
+}
+
+// limitedCollector restricts the number of concurrent Collect calls,
+// using a shared semaphore.
+type limitedCollector struct {
+	prometheus.Collector
+	sem chan struct{}
+}
+
+func (l *limitedCollector) Collect(ch chan<- prometheus.Metric) {
+	l.sem <- struct{}{}
+	defer func() { <-l.sem }()
+	l.Collector.Collect(ch)
+}
+
+// aggregateMetricsHandler serves the metrics of all AP groups and portals,
+// with a "target" label to tell them apart.
+func (c *Client) aggregateMetricsHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	reg, err := c.aggregateRegistry(r.Context())
+	if err != nil {
+		c.httpError(w, r, err)
+		return
+	}
+
+	h := promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+}
+
+func (c *Client) aggregateRegistry(ctx context.Context) (*prometheus.Registry, error) {
+	apGroups, err := c.api.fetchAPGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	portals, err := c.api.fetchGuestPortals(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &c.Aggregate
+	var targets []string
+	for _, name := range filter(apGroups, a.includeAPGroups, a.excludeAPGroups) {
+		targets = append(targets, groupTarget(name))
+	}
+	for _, name := range filter(portals, a.includePortals, a.excludePortals) {
+		targets = append(targets, portalTarget(name))
+	}
+
+	reg := prometheus.NewRegistry()
+	portalCount := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "portals_count",
+		Help:      "number of guest access portals",
+	})
+	portalCount.Set(float64(len(portals)))
+	reg.MustRegister(portalCount)
+
+	sem := make(chan struct{}, a.Concurrency)
+	for _, target := range targets {
+		coll, err := c.collector(ctx, target, nil)
+		if err != nil {
+			return nil, err
+		}
+		wrapped := prometheus.WrapRegistererWith(prometheus.Labels{"target": target}, reg)
+		if err := wrapped.Register(&limitedCollector{Collector: coll, sem: sem}); err != nil {
+			return nil, fmt.Errorf("failed to register collector for %s: %w", target, err)
+		}
+	}
+	return reg, nil
+}
@@ -0,0 +1,45 @@
+package exporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/digineo/cambium-exporter/cnmaestrotest"
+)
+
+func TestAggregatePortalCount(t *testing.T) {
+	f := cnmaestrotest.DemoFixtures()
+	f.Portals = append(f.Portals, cnmaestrotest.Portal{Name: "StaffPortal"})
+	srv := newTestServer(t, f)
+	c := newTestClient(t, srv, "")
+	login(t, c)
+
+	reg, err := c.aggregateRegistry(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found bool
+	for _, mf := range mfs {
+		if mf.GetName() != "cambium_maestro_portals_count" {
+			continue
+		}
+		found = true
+		if n := len(mf.GetMetric()); n != 1 {
+			t.Fatalf("expected a single portal count, got %d", n)
+		}
+		if v := mf.GetMetric()[0].GetGauge().GetValue(); v != float64(len(f.Portals)) {
+			t.Errorf("expected %d portals, got %v", len(f.Portals), v)
+		}
+	}
+	if !found {
+		t.Error("missing metric cambium_maestro_portals_count")
+	}
+	if n := srv.Requests(apiPrefix + "/services/guest/portal"); n != 1 {
+		t.Errorf("expected the portal list to be fetched once, got %d requests", n)
+	}
+}
@@ -22,6 +22,7 @@ type responseBuffer struct {
 
 var bpool = &sync.Pool{
 	New: func() interface{} {
+		bpoolAllocs.Add(1)
 		return new(responseBuffer)
 	},
 }
@@ -32,8 +33,74 @@ func (rb *responseBuffer) Close() error {
 	return nil
 }
 
-// newRequest creates a new HTTP request and prefills its header.
+// fetch performs an API request. If the controller rejects the session,
+// fetch logs in again and retries the request once. Non-2xx responses
+// are returned as *APIError.
 func (c *Client) fetch(ctx context.Context, method, path string, params url.Values) (*http.Response, error) {
+	gen := c.sessionGeneration()
+	res, err := c.do(ctx, method, path, params)
+	if err == nil && c.shouldRelogin(ctx, res.StatusCode) {
+		res.Body.Close()
+		c.log.Infof("session rejected with status %d, logging in again", res.StatusCode)
+		c.setSessionValid(false)
+		if err = c.relogin(ctx, gen); err != nil {
+			return nil, fmt.Errorf("session expired, and login failed: %w", err)
+		}
+		res, err = c.do(ctx, method, path, params)
+		if err == nil && isAuthFailure(res.StatusCode) {
+			c.suspendRelogin()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+		return nil, newAPIError(method, path, res)
+	}
+	return res, nil
+}
+
+func isAuthFailure(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+// shouldRelogin reports whether a response status means that the session
+// has expired. As 403 Forbidden is also returned for endpoints the user
+// may not access, the session is then checked with /user/me.
+//
+// Re-logins are suspended for a while, after one did not help. Otherwise,
+// each scrape would log in again, which might get the account locked.
+func (c *Client) shouldRelogin(ctx context.Context, status int) bool {
+	if !isAuthFailure(status) {
+		return false
+	}
+
+	c.mu.Lock()
+	suspended := time.Until(c.reloginSuspended)
+	c.mu.Unlock()
+	if suspended > 0 {
+		c.log.Errorf("session rejected with status %d, re-login suspended for %v", status, suspended.Round(time.Second))
+		return false
+	}
+
+	return status == http.StatusUnauthorized || c.validateSession(ctx) != nil
+}
+
+// suspendRelogin prevents shouldRelogin from triggering a login, until
+// sessionReloginBackoff has passed.
+func (c *Client) suspendRelogin() {
+	c.log.Errorf("request rejected after logging in again, suspending re-logins for %v", sessionReloginBackoff)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reloginSuspended = time.Now().Add(sessionReloginBackoff)
+}
+
+// do creates a new HTTP request, prefills its header, and buffers the
+// response body.
+func (c *Client) do(ctx context.Context, method, path string, params url.Values) (*http.Response, error) {
 	u2 := *c.instance // dup
 	u2.Path = "/0/cn-srv"
 	if len(path) > 0 && path[0] != '/' {
@@ -59,7 +126,9 @@ func (c *Client) fetch(ctx context.Context, method, path string, params url.Valu
 
 	t0 := time.Now()
 	res, err := c.client.Do(req)
+	c.metrics.observeFetch(path, res, err, t0)
 	if err == nil {
+		bpoolGets.Add(1)
 		buf, _ := bpool.Get().(*responseBuffer)
 		_, _ = io.Copy(buf, res.Body)
 		res.Body.Close()
@@ -133,6 +202,7 @@ var fetchDevFields = []string{
 
 	"$radios",
 	"id", "rxAvg", "txAvg", "band", "radios.mac", "channel", "chWidth", "rfqlt", "pow",
+	"noiseFloor", "chUtil", "intf", "txRetries", "txErr", "staCnt",
 }
 
 func (c *Client) fetchDevices(ctx context.Context, apGroup string) ([]*Device, error) {
@@ -169,6 +239,43 @@ func (c *Client) fetchDevices(ctx context.Context, apGroup string) ([]*Device, e
 	return devs, nil
 }
 
+var fetchClientFields = []string{
+	"apMac", "ssid", "rssi", "snr", "radio.id", "radio.band",
+}
+
+// fetchClients returns the wireless clients connected to the APs of an
+// AP group.
+func (c *Client) fetchClients(ctx context.Context, apGroup string) ([]*WirelessClient, error) {
+	path := fmt.Sprintf("/stats/profiles/%s/clients", apGroup)
+
+	res, err := c.fetch(ctx, http.MethodGet, path, url.Values{
+		"fields": {strings.Join(fetchClientFields, ",")},
+		"limit":  {"0"},
+		"offset": {"0"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch clients for AP group %q: %w", apGroup, err)
+	}
+
+	defer res.Body.Close()
+	var data struct {
+		Data struct {
+			Profiles struct {
+				Clients []clientAPIResponse `json:"clients"`
+			} `json:"profiles"`
+		} `json:"data"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode client response data for AP group %q: %w", apGroup, err)
+	}
+
+	clients := make([]*WirelessClient, 0, len(data.Data.Profiles.Clients))
+	for i := range data.Data.Profiles.Clients {
+		clients = append(clients, data.Data.Profiles.Clients[i].Normalize())
+	}
+	return clients, nil
+}
+
 func (c *Client) fetchAPGroupData(ctx context.Context, apGroup string) (*APGroupAPIResponse, error) {
 	fields := fmt.Sprintf("name,deviceCount,offlineCount,clientCount,clientCount24h,name:%s", apGroup)
 	res, err := c.fetch(ctx, http.MethodGet, "/config/profiles", url.Values{"fields": {fields}})
@@ -186,38 +293,50 @@ func (c *Client) fetchAPGroupData(ctx context.Context, apGroup string) (*APGroup
 		return nil, fmt.Errorf("failed to decode device response data for AP group %q: %w", apGroup, err)
 	}
 	if len(data.Data.Profiles) == 0 {
-		return nil, nil
+		return nil, fmt.Errorf("AP group %q: %w", apGroup, errNotFound)
 	}
 
 	apg := data.Data.Profiles[0]
 	return &apg, nil
 }
 
+const portalsPerPage = 100
+
 func (c *Client) fetchGuestPortals(ctx context.Context) ([]string, error) {
-	res, err := c.fetch(ctx, http.MethodGet, "/services/guest/portal", url.Values{
-		"limit":  {"10"},
-		"offset": {"0"},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch guest portal list: %w", err)
-	}
+	var names []string
 
-	defer res.Body.Close()
-	var data struct {
-		Data struct {
-			Portals []PortalAPIResponse `json:"result"`
-		} `json:"data"`
-	}
-	if err = json.NewDecoder(res.Body).Decode(&data); err != nil {
-		return nil, fmt.Errorf("failed to decode guest portal response: %w", err)
-	}
-	if len(data.Data.Portals) == 0 {
-		return nil, nil
-	}
+	for offset := 0; ; {
+		res, err := c.fetch(ctx, http.MethodGet, "/services/guest/portal", url.Values{
+			"limit":  {strconv.Itoa(portalsPerPage)},
+			"offset": {strconv.Itoa(offset)},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch guest portal list (offset %d): %w", offset, err)
+		}
 
-	names := make([]string, 0, len(data.Data.Portals))
-	for _, portal := range data.Data.Portals {
-		names = append(names, portal.Name)
+		var data struct {
+			Data struct {
+				Meta struct {
+					Total int `json:"totalCount"`
+				} `json:"_metadata"`
+				Portals []PortalAPIResponse `json:"result"`
+			} `json:"data"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&data)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode guest portal response: %w", err)
+		}
+
+		for _, portal := range data.Data.Portals {
+			names = append(names, portal.Name)
+		}
+
+		// the controller may return less than requested per page
+		offset += len(data.Data.Portals)
+		if len(data.Data.Portals) == 0 || offset >= data.Data.Meta.Total {
+			break
+		}
 	}
 	return names, nil
 }
@@ -231,7 +350,7 @@ func (c *Client) fetchPortalSessions(ctx context.Context, name string) ([]*Porta
 	for page := 0; ; page++ {
 		data, err := c.fetchPortalSessionsPage(ctx, name, page)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to decode portal session data for portal %s (page %d): %w", name, page, err)
+			return nil, 0, fmt.Errorf("failed to fetch portal session data for portal %s (page %d): %w", name, page, err)
 		}
 		for _, s := range data.Sessions {
 			count[s.DeviceMAC]++
@@ -266,6 +385,8 @@ func (c *Client) fetchPortalSessionsPage(ctx context.Context, name string, page
 		Data SessionsAPIResponse `json:"data"`
 	}
 
-	err = json.NewDecoder(res.Body).Decode(&data)
-	return &data.Data, err
+	if err = json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &data.Data, nil
 }
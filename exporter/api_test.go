@@ -0,0 +1,103 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/digineo/cambium-exporter/cnmaestrotest"
+)
+
+func TestForbiddenWithValidSession(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, "")
+	login(t, c)
+
+	const path = apiPrefix + "/config/profiles"
+	srv.InjectError(path, http.StatusForbidden, 1)
+
+	_, err := c.api.fetchAPGroups(context.Background())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 error, got %v", err)
+	}
+	if n := srv.Logins(); n != 1 {
+		t.Errorf("expected no re-login for a valid session, got %d logins", n)
+	}
+	if n := srv.Requests(apiPrefix + "/user/me"); n != 1 {
+		t.Errorf("expected the session to be checked once, got %d requests", n)
+	}
+}
+
+func TestForbiddenWithExpiredSession(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, "")
+	login(t, c)
+
+	const path = apiPrefix + "/config/profiles"
+	srv.ExpireSession()
+	srv.InjectError(path, http.StatusForbidden, 1)
+
+	if _, err := c.api.fetchAPGroups(context.Background()); err != nil {
+		t.Fatalf("expected request to succeed after re-login, got %v", err)
+	}
+	if n := srv.Logins(); n != 2 {
+		t.Errorf("expected 2 logins, got %d", n)
+	}
+	if n := srv.Requests(path); n != 2 {
+		t.Errorf("expected the rejected request to be retried once, got %d requests", n)
+	}
+}
+
+func TestReloginSuspended(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, "")
+	login(t, c)
+
+	const path = apiPrefix + "/config/profiles"
+	srv.InjectError(path, http.StatusUnauthorized, -1)
+
+	for range 3 {
+		if _, err := c.api.fetchAPGroups(context.Background()); err == nil {
+			t.Fatal("expected request to fail")
+		}
+	}
+	if n := srv.Logins(); n != 2 {
+		t.Errorf("expected a single re-login, got %d logins", n)
+	}
+	if n := srv.Requests(path); n != 4 {
+		t.Errorf("expected 4 requests, got %d", n)
+	}
+}
+
+func TestConcurrentRelogin(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, "")
+	login(t, c)
+	srv.ExpireSession()
+
+	const scrapes = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, scrapes)
+	start := make(chan struct{})
+	for range scrapes {
+		wg.Go(func() {
+			<-start
+			if snap := c.groupSnapshot(context.Background(), "Default", true); snap.Err != nil {
+				errs <- snap.Err
+			}
+		})
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("scrape failed: %v", err)
+	}
+	if n := srv.Logins(); n != 2 {
+		t.Errorf("expected exactly one re-login, got %d logins", n-1)
+	}
+}
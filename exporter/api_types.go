@@ -2,6 +2,7 @@ package exporter
 
 import (
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,7 +13,7 @@ type rebootResponse struct {
 
 type radioResponse struct {
 	ID           int    `json:"id"`
-	Band         string `json:"band"` // enum: { "2.4GHz", "5GHz" }
+	Band         string `json:"band"` // enum: { "2.4GHz", "5GHz", "6GHz" }
 	ChannelWidth string `json:"chWidth"`
 	Channel      string `json:"channel"`
 	MAC          string `json:"mac"`
@@ -20,6 +21,14 @@ type radioResponse struct {
 	Quality      int    `json:"rfqlt"` // percentage points
 	RxAvg        int    `json:"rxAvg"` // in kbps
 	TxAvg        int    `json:"txAvg"` // in kbps
+
+	// Health statistics, not reported by all firmware versions.
+	NoiseFloor   *int `json:"noiseFloor"` // in dBm
+	ChannelUtil  *int `json:"chUtil"`     // percentage points
+	Interference *int `json:"intf"`       // percentage points
+	TxRetries    *int `json:"txRetries"`  // percentage points
+	TxErrors     *int `json:"txErr"`      // percentage points
+	Stations     *int `json:"staCnt"`     // number of associated stations
 }
 
 // DeviceAPIResponse holds the data returned from the controller's
@@ -49,6 +58,7 @@ type DeviceAPIResponse struct {
 }
 
 type Radio struct {
+	ID           int // radio index, unique per device
 	Band         Band
 	Channel      int // channel number
 	ChannelWidth int // in MHz
@@ -56,30 +66,39 @@ type Radio struct {
 	Quality      int // 0..100
 	Rx           int // average rate in kbps
 	Tx           int // average rate in kbps
+
+	// Health statistics, nil if not reported.
+	NoiseFloor   *int // in dBm
+	ChannelUtil  *int // 0..100
+	Interference *int // 0..100
+	TxRetries    *int // 0..100
+	TxErrors     *int // 0..100
+	Stations     *int
 }
 
 func (radio radioResponse) Normalize() (r Radio) {
 	r = Radio{
-		Band:         BandUnknown,
+		ID:           radio.ID,
+		Band:         parseBand(radio.Band),
 		Channel:      -1,
 		ChannelWidth: -1,
 		Rx:           radio.RxAvg,
 		Tx:           radio.TxAvg,
 		Quality:      radio.Quality,
 		Power:        radio.Power,
-	}
-
-	switch radio.Band {
-	case "2.4GHz", "2.4 GHz":
-		r.Band = BandBGN
-	case "5GHz", "5 GHz":
-		r.Band = BandAC
+		NoiseFloor:   radio.NoiseFloor,
+		ChannelUtil:  radio.ChannelUtil,
+		Interference: radio.Interference,
+		TxRetries:    radio.TxRetries,
+		TxErrors:     radio.TxErrors,
+		Stations:     radio.Stations,
 	}
 
 	if ch, err := strconv.Atoi(radio.Channel); err == nil {
 		r.Channel = ch
 	}
-	if chw, err := strconv.Atoi(radio.ChannelWidth); err == nil {
+	chWidth := strings.TrimSpace(strings.TrimSuffix(radio.ChannelWidth, "MHz"))
+	if chw, err := strconv.Atoi(chWidth); err == nil {
 		r.ChannelWidth = chw
 	}
 	return
@@ -90,9 +109,22 @@ type Band string
 const (
 	BandAC      = Band("5")
 	BandBGN     = Band("2.4")
+	Band6E      = Band("6") // Wi-Fi 6E
 	BandUnknown = Band("unknown")
 )
 
+func parseBand(s string) Band {
+	switch s {
+	case "2.4GHz", "2.4 GHz":
+		return BandBGN
+	case "5GHz", "5 GHz":
+		return BandAC
+	case "6GHz", "6 GHz":
+		return Band6E
+	}
+	return BandUnknown
+}
+
 // Device holds basic metrics for a single WiFi AP.
 // It is constructed from a DeviceAPIResponse.
 type Device struct {
@@ -102,6 +134,7 @@ type Device struct {
 	SiteName        string
 	Hostname        string
 	FirmwareVersion string
+	Online          bool
 	Uptime          *time.Time
 	Downtime        *time.Time
 	LastRebootAt    *time.Time
@@ -123,6 +156,7 @@ func (api *DeviceAPIResponse) Normalize() *Device {
 		SiteName:        api.SiteName,
 		Hostname:        api.Config.Name,
 		FirmwareVersion: api.Management.FirmwareVersion,
+		Online:          api.System.Online,
 	}
 
 	if api.System.Online {
@@ -152,6 +186,86 @@ func (api *DeviceAPIResponse) Normalize() *Device {
 	return dev
 }
 
+const restStatusOnline = "online"
+
+// restDeviceResponse holds the data returned from the northbound API's
+// device statistics endpoint.
+type restDeviceResponse struct {
+	Model            string `json:"product"`
+	MAC              string `json:"mac"`
+	Serial           string `json:"msn"`
+	SiteName         string `json:"site"`
+	Hostname         string `json:"name"`
+	FirmwareVersion  string `json:"software_version"`
+	Status           string `json:"status"`      // enum: { "online", "offline", "onboarding" }
+	StatusTime       int64  `json:"status_time"` // unix timestamp of last status change
+	RebootReason     string `json:"last_reboot_reason"`
+	ConnectedClients int    `json:"connected_clients"`
+
+	Radios []struct {
+		ID           int    `json:"id"`
+		Band         string `json:"band"`
+		Channel      string `json:"channel"`
+		ChannelWidth string `json:"channel_width"`
+		MAC          string `json:"mac"`
+		Power        int    `json:"tx_power"`
+		Quality      int    `json:"rf_quality"`
+		RxBps        int    `json:"rx_bps"`
+		TxBps        int    `json:"tx_bps"`
+
+		NoiseFloor   *int `json:"noise_floor"`
+		ChannelUtil  *int `json:"channel_utilization"`
+		Interference *int `json:"interference"`
+		TxRetries    *int `json:"tx_retries"`
+		TxErrors     *int `json:"tx_errors"`
+		Stations     *int `json:"clients"`
+	} `json:"radios"`
+}
+
+func (api *restDeviceResponse) Normalize() *Device {
+	dev := &Device{
+		Model:           api.Model,
+		MAC:             api.MAC,
+		Serial:          api.Serial,
+		SiteName:        api.SiteName,
+		Hostname:        api.Hostname,
+		FirmwareVersion: api.FirmwareVersion,
+		RebootReason:    api.RebootReason,
+		Online:          api.Status == restStatusOnline,
+	}
+
+	if api.StatusTime > 0 {
+		t := time.Unix(api.StatusTime, 0)
+		if dev.Online {
+			dev.Uptime = &t
+		} else {
+			dev.Downtime = &t
+		}
+	}
+
+	for _, radio := range api.Radios {
+		dev.Radios = append(dev.Radios, radioResponse{
+			ID:           radio.ID,
+			Band:         radio.Band,
+			Channel:      radio.Channel,
+			ChannelWidth: radio.ChannelWidth,
+			MAC:          radio.MAC,
+			Power:        radio.Power,
+			Quality:      radio.Quality,
+			RxAvg:        radio.RxBps / kbps,
+			TxAvg:        radio.TxBps / kbps,
+			NoiseFloor:   radio.NoiseFloor,
+			ChannelUtil:  radio.ChannelUtil,
+			Interference: radio.Interference,
+			TxRetries:    radio.TxRetries,
+			TxErrors:     radio.TxErrors,
+			Stations:     radio.Stations,
+		}.Normalize())
+	}
+
+	return dev
+}
+
 type APGroupAPIResponse struct {
 	Name             string `json:"name"`
 	DevicesCount     int    `json:"deviceCount"`
@@ -192,3 +306,62 @@ type PortalSession struct {
 	PortalName string
 	Sessions   int
 }
+
+// clientAPIResponse holds the data of a wireless client, as returned
+// from the controller's client endpoint.
+type clientAPIResponse struct {
+	DeviceMAC string `json:"apMac"`
+	SSID      string `json:"ssid"`
+	RSSI      int    `json:"rssi"` // in dBm
+	SNR       int    `json:"snr"`  // in dB
+	// MAC      string `json:"mac"`      // omitted, PII
+	// IP       string `json:"ip"`       // omitted, PII
+	// Hostname string `json:"hostname"` // omitted, PII
+
+	Radio struct {
+		ID   *int   `json:"id"` // radio index, 0 is valid
+		Band string `json:"band"`
+	} `json:"radio"`
+}
+
+func (api *clientAPIResponse) Normalize() *WirelessClient {
+	return &WirelessClient{
+		DeviceMAC: api.DeviceMAC,
+		RadioID:   api.Radio.ID,
+		Band:      parseBand(api.Radio.Band),
+		SSID:      api.SSID,
+		RSSI:      api.RSSI,
+		SNR:       api.SNR,
+	}
+}
+
+// restClientResponse holds the data of a wireless client, as returned
+// from the northbound API's client endpoint.
+type restClientResponse struct {
+	DeviceMAC string `json:"ap_mac"`
+	SSID      string `json:"ssid"`
+	Band      string `json:"band"`
+	RSSI      int    `json:"rssi"`
+	SNR       int    `json:"snr"`
+}
+
+func (api *restClientResponse) Normalize() *WirelessClient {
+	return &WirelessClient{
+		DeviceMAC: api.DeviceMAC,
+		Band:      parseBand(api.Band),
+		SSID:      api.SSID,
+		RSSI:      api.RSSI,
+		SNR:       api.SNR,
+	}
+}
+
+// WirelessClient is a client connected to an AP. It intentionally
+// carries no client identifiers.
+type WirelessClient struct {
+	DeviceMAC string // AP MAC address
+	RadioID   *int   // nil, if unknown
+	Band      Band
+	SSID      string
+	RSSI      int // in dBm, 0 if unknown
+	SNR       int // in dB, 0 if unknown
+}
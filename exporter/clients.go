@@ -0,0 +1,110 @@
+package exporter
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Histogram buckets for client signal metrics.
+var (
+	rssiBuckets = []float64{-90, -80, -70, -67, -60, -50, -40, -30} // dBm
+	snrBuckets  = []float64{5, 10, 15, 20, 25, 30, 40, 50}          // dB
+)
+
+// histogram accumulates observations for prometheus.MustNewConstHistogram.
+type histogram struct {
+	buckets []float64
+	counts  []uint64 // non-cumulative, per bucket
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) metric(desc *prometheus.Desc, labels ...string) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(h.buckets))
+	var n uint64
+	for i, upper := range h.buckets {
+		n += h.counts[i]
+		buckets[upper] = n
+	}
+	return prometheus.MustNewConstHistogram(desc, h.count, h.sum, buckets, labels...)
+}
+
+// apClients holds the client statistics of a single AP.
+type apClients struct {
+	total  int
+	radios map[int]int    // key = radio ID
+	ssids  map[string]int // key = SSID
+	rssi   *histogram
+	snr    *histogram
+}
+
+// clientStats aggregates the clients per AP. Every device gets an entry,
+// even without clients. Clients without radio ID (see restAPI.fetchClients)
+// are assigned to the device's first radio on the same band.
+func clientStats(devices []*Device, clients []*WirelessClient) map[string]*apClients {
+	stats := make(map[string]*apClients, len(devices))
+	radioIDs := make(map[string]map[Band]int, len(devices)) // key = AP MAC
+
+	for _, dev := range devices {
+		mac := strings.ToLower(dev.MAC)
+		stats[mac] = &apClients{
+			radios: make(map[int]int, len(dev.Radios)),
+			ssids:  make(map[string]int),
+			rssi:   newHistogram(rssiBuckets),
+			snr:    newHistogram(snrBuckets),
+		}
+		radioIDs[mac] = make(map[Band]int, len(dev.Radios))
+		for _, r := range dev.Radios {
+			stats[mac].radios[r.ID] = 0
+			if _, ok := radioIDs[mac][r.Band]; !ok {
+				radioIDs[mac][r.Band] = r.ID
+			}
+		}
+	}
+
+	for _, cl := range clients {
+		mac := strings.ToLower(cl.DeviceMAC)
+		ap := stats[mac]
+		if ap == nil {
+			continue // AP not in this group
+		}
+
+		ap.total++
+		id, ok := radioIDs[mac][cl.Band]
+		if cl.RadioID != nil {
+			id, ok = *cl.RadioID, true
+		}
+		if _, known := ap.radios[id]; ok && known {
+			ap.radios[id]++
+		}
+		if cl.SSID != "" {
+			ap.ssids[cl.SSID]++
+		}
+		if cl.RSSI != 0 {
+			ap.rssi.observe(float64(cl.RSSI))
+		}
+		if cl.SNR != 0 {
+			ap.snr.observe(float64(cl.SNR))
+		}
+	}
+	return stats
+}
@@ -0,0 +1,41 @@
+package exporter
+
+import (
+	"encoding/json"
+	"maps"
+	"testing"
+)
+
+func TestClientStatsRadioID(t *testing.T) {
+	const mac = "00:04:56:00:00:01"
+	devices := []*Device{{
+		MAC: mac,
+		Radios: []Radio{
+			{ID: 0, Band: BandBGN},
+			{ID: 1, Band: BandAC},
+			{ID: 2, Band: BandAC},
+		},
+	}}
+
+	var clients []*WirelessClient
+	for _, data := range []string{
+		`{"apMac":"00:04:56:00:00:01","radio":{"id":0,"band":"5GHz"}}`, // radio 0, despite the band
+		`{"apMac":"00:04:56:00:00:01","radio":{"id":2,"band":"5GHz"}}`,
+		`{"apMac":"00:04:56:00:00:01","radio":{"band":"5GHz"}}`, // first radio on the band
+		`{"apMac":"00:04:56:00:00:01","radio":{"band":"6GHz"}}`, // no radio on the band
+	} {
+		var res clientAPIResponse
+		if err := json.Unmarshal([]byte(data), &res); err != nil {
+			t.Fatal(err)
+		}
+		clients = append(clients, res.Normalize())
+	}
+
+	ap := clientStats(devices, clients)[mac]
+	if ap.total != len(clients) {
+		t.Errorf("expected %d clients, got %d", len(clients), ap.total)
+	}
+	if want := map[int]int{0: 1, 1: 1, 2: 1}; !maps.Equal(ap.radios, want) {
+		t.Errorf("expected clients per radio %v, got %v", want, ap.radios)
+	}
+}
@@ -2,6 +2,8 @@ package exporter
 
 import (
 	"context"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -11,6 +13,7 @@ type Collector struct {
 	client  *Client
 	apGroup string          // WiFi AP Group name
 	ctx     context.Context // HTTP request context
+	module  *Module         // selected metrics, nil for all
 }
 
 type PortalCollector struct {
@@ -27,7 +30,10 @@ var (
 const namespace = "cambium_maestro"
 
 var (
-	ctrlUp = prometheus.NewDesc(namespace+"up", "indicator whether cloud controller is reachable", nil, nil)
+	ctrlUp            = prometheus.NewDesc(namespace+"_up", "indicator whether cloud controller is reachable", nil, nil)
+	ctrlUpLegacy      = prometheus.NewDesc(namespace+"up", "indicator whether cloud controller is reachable (deprecated, see cambium_maestro_up)", nil, nil)
+	scrapeDuration    = prometheus.NewDesc(namespace+"_scrape_duration_seconds", "duration of the scrape in seconds", nil, nil)
+	scrapeErrorsTotal = prometheus.NewDesc(namespace+"_scrape_errors_total", "number of failed fetches from the controller", []string{"stage"}, nil)
 
 	groupLabels           = []string{"name"} // used in groupDesc
 	groupDevicesCount     = groupDesc("devices_count", "number of adopted devices")
@@ -37,24 +43,44 @@ var (
 	groupClientCount24H   = groupDesc("client_count_24h", "number of clients seen in the past 24 hours")
 
 	apLabels   = []string{"apgroup", "mac"} // used in apDesc
-	apUp       = apDesc("up", "details for AP", "model", "hostname", "serial", "site", "firmware")
+	apUp       = apDesc("up", "details for AP (deprecated, see ap_online and ap_info)", "model", "hostname", "serial", "site", "firmware")
+	apOnline   = apDesc("online", "whether the AP is online (1) or offline (0)")
+	apInfo     = apDesc("info", "details for AP", "model", "hostname", "serial", "site", "firmware")
 	apUptime   = apDesc("uptime", "number of uptime seconds")
 	apDowntime = apDesc("downtime", "number of downtime seconds")
 	apReboot   = apDesc("reboot", "number of seconds since last reboot", "reason")
 
-	radioLabels       = []string{"apgroup", "ap", "band"} // used in radioDesc
+	apClientCount     = apDesc("clients_count", "number of connected clients")
+	apSSIDClientCount = apDesc("ssid_clients_count", "number of connected clients per SSID", "ssid")
+	apClientRSSI      = apDesc("client_rssi_dbm", "distribution of the clients' signal strength in dBm")
+	apClientSNR       = apDesc("client_snr_db", "distribution of the clients' signal-to-noise ratio in dB")
+
+	radioLabels       = []string{"apgroup", "ap", "band", "radio"} // used in radioDesc
 	radioChannel      = radioDesc("channel", "WiFi channel number")
 	radioChannelWidth = radioDesc("channel_width", "WiFi channel width in MHz")
 	radioPower        = radioDesc("power", "RF transmit power")
 	radioQuality      = radioDesc("quality", "RF quality measurement in percentage points")
 	radioXfer         = radioDesc("transfer_rate", "current traffic rate in bps", "direction")
+	radioClientCount  = radioDesc("clients_count", "number of connected clients")
+	radioNoiseFloor   = radioDesc("noise_floor", "noise floor in dBm")
+	radioChannelUtil  = radioDesc("channel_utilization", "channel utilization (airtime) in percentage points")
+	radioInterference = radioDesc("interference", "interference in percentage points")
+	radioTxRetries    = radioDesc("tx_retries", "transmit retries in percentage points")
+	radioTxErrors     = radioDesc("tx_errors", "transmit errors in percentage points")
+	radioStations     = radioDesc("stations_count", "number of associated stations, as reported by the AP")
 
 	portalSessions   = prometheus.NewDesc(namespace+"_sessions_count", "number of active sessions", []string{"name"}, nil)
 	portalAPSessions = prometheus.NewDesc(namespace+"_ap_sessions_count", "number of active sessions", []string{"portal", "mac"}, nil)
+
+	snapshotAge = prometheus.NewDesc(namespace+"_snapshot_age_seconds", "number of seconds since the data was fetched (only with polling)", nil, nil)
 )
 
 func (*Collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- ctrlUp
+	ch <- ctrlUpLegacy
+	ch <- scrapeDuration
+	ch <- scrapeErrorsTotal
+	ch <- snapshotAge
 
 	ch <- groupDevicesCount
 	ch <- groupDevicesOffline
@@ -63,21 +89,36 @@ func (*Collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- groupClientCount24H
 
 	ch <- apUp
+	ch <- apOnline
+	ch <- apInfo
 	ch <- apUptime
 	ch <- apDowntime
 	ch <- apReboot
+	ch <- apClientCount
+	ch <- apSSIDClientCount
+	ch <- apClientRSSI
+	ch <- apClientSNR
 
 	ch <- radioChannel
 	ch <- radioChannelWidth
 	ch <- radioPower
 	ch <- radioQuality
 	ch <- radioXfer
+	ch <- radioClientCount
+	ch <- radioNoiseFloor
+	ch <- radioChannelUtil
+	ch <- radioInterference
+	ch <- radioTxRetries
+	ch <- radioTxErrors
+	ch <- radioStations
 }
 
 const kbps = 1000
 
 func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 	c.client.log.Debugf("collecting metrics for %s", c.apGroup)
+	start := time.Now()
+	target := groupTarget(c.apGroup)
 
 	metric := func(desc *prometheus.Desc, v float64, labels ...string) {
 		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...)
@@ -85,6 +126,11 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 	intMetric := func(desc *prometheus.Desc, v int, labels ...string) {
 		metric(desc, float64(v), labels...)
 	}
+	optMetric := func(desc *prometheus.Desc, v *int, labels ...string) {
+		if v != nil {
+			intMetric(desc, *v, labels...)
+		}
+	}
 	now := time.Now()
 	timeMetric := func(desc *prometheus.Desc, v *time.Time, labels ...string) {
 		if v != nil {
@@ -92,23 +138,27 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 		}
 	}
 
-	group, err := c.client.fetchAPGroupData(c.ctx, c.apGroup)
-	if err != nil {
-		c.client.log.Errorf("fetching AP group data for %s failed with %v", c.apGroup, err)
-		metric(ctrlUp, 0)
-		return
+	snap := c.client.groupSnapshot(c.ctx, c.apGroup, c.module.selectsAny(clientFamilies))
+	if c.client.poller != nil {
+		timeMetric(snapshotAge, &snap.Time)
 	}
-
-	devices, err := c.client.fetchDevices(c.ctx, c.apGroup)
-	if err != nil {
-		c.client.log.Errorf("fetching device data for %s failed with %v", c.apGroup, err)
-		metric(ctrlUp, 0)
+	if snap.Err != nil {
+		c.client.log.Errorf("fetching data for AP group %s failed with %v", c.apGroup, snap.Err)
+		c.client.collectScrape(ch, target, groupStages, snap.Err, start)
 		return
 	}
 
-	metric(ctrlUp, 1)
-
+	group, devices := snap.Group, snap.Devices
 	name := group.Name
+
+	var clients map[string]*apClients // nil, if clients are not available
+	switch {
+	case snap.ClientsErr != nil:
+		c.client.log.Errorf("fetching clients for AP group %s failed with %v", c.apGroup, snap.ClientsErr)
+	case !snap.NoClients:
+		clients = clientStats(devices, snap.Clients)
+	}
+
 	intMetric(groupDevicesCount, group.DevicesCount, name)
 	intMetric(groupDevicesOffline, group.DevicesOffline, name)
 	intMetric(groupDevicesOutOfSync, group.DevicesOutOfSync, name)
@@ -117,52 +167,93 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 
 	for _, dev := range devices {
 		mac := dev.MAC
-		metric(apUp, 1, name, mac, dev.Model, dev.Hostname, dev.Serial, dev.SiteName, dev.FirmwareVersion)
+		metric(apInfo, 1, name, mac, dev.Model, dev.Hostname, dev.Serial, dev.SiteName, dev.FirmwareVersion)
+		if c.client.ExportLegacyAPUp {
+			metric(apUp, 1, name, mac, dev.Model, dev.Hostname, dev.Serial, dev.SiteName, dev.FirmwareVersion)
+		}
+		if dev.Online {
+			metric(apOnline, 1, name, mac)
+		} else {
+			metric(apOnline, 0, name, mac)
+		}
 
 		timeMetric(apUptime, dev.Uptime, name, mac)
 		timeMetric(apDowntime, dev.Downtime, name, mac)
 		timeMetric(apReboot, dev.LastRebootAt, name, mac, dev.RebootReason)
 
+		cl := clients[strings.ToLower(mac)]
+		if cl != nil {
+			intMetric(apClientCount, cl.total, name, mac)
+			for ssid, n := range cl.ssids {
+				intMetric(apSSIDClientCount, n, name, mac, ssid)
+			}
+			ch <- cl.rssi.metric(apClientRSSI, name, mac)
+			ch <- cl.snr.metric(apClientSNR, name, mac)
+		}
+
 		for _, r := range dev.Radios {
-			band := string(r.Band)
-			intMetric(radioChannel, r.Channel, name, mac, band)
-			intMetric(radioChannelWidth, r.ChannelWidth, name, mac, band)
-			intMetric(radioPower, r.Power, name, mac, band)
-			intMetric(radioQuality, r.Quality, name, mac, band)
+			band, id := string(r.Band), strconv.Itoa(r.ID)
+			intMetric(radioChannel, r.Channel, name, mac, band, id)
+			intMetric(radioChannelWidth, r.ChannelWidth, name, mac, band, id)
+			intMetric(radioPower, r.Power, name, mac, band, id)
+			intMetric(radioQuality, r.Quality, name, mac, band, id)
 
 			// controller reports kBit/s, we export Bit/s
-			intMetric(radioXfer, r.Tx*kbps, name, mac, band, "out")
-			intMetric(radioXfer, r.Rx*kbps, name, mac, band, "in")
+			intMetric(radioXfer, r.Tx*kbps, name, mac, band, id, "out")
+			intMetric(radioXfer, r.Rx*kbps, name, mac, band, id, "in")
+
+			optMetric(radioNoiseFloor, r.NoiseFloor, name, mac, band, id)
+			optMetric(radioChannelUtil, r.ChannelUtil, name, mac, band, id)
+			optMetric(radioInterference, r.Interference, name, mac, band, id)
+			optMetric(radioTxRetries, r.TxRetries, name, mac, band, id)
+			optMetric(radioTxErrors, r.TxErrors, name, mac, band, id)
+			optMetric(radioStations, r.Stations, name, mac, band, id)
+
+			if cl != nil {
+				intMetric(radioClientCount, cl.radios[r.ID], name, mac, band, id)
+			}
 		}
 	}
+
+	c.client.collectScrape(ch, target, groupStages, nil, start)
 }
 
 func (p *PortalCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- ctrlUp
+	ch <- ctrlUpLegacy
+	ch <- scrapeDuration
+	ch <- scrapeErrorsTotal
+	ch <- snapshotAge
 	ch <- portalSessions
 	ch <- portalAPSessions
 }
 
 func (c *PortalCollector) Collect(ch chan<- prometheus.Metric) {
 	c.client.log.Debugf("collecting metrics for portal %s", c.portal)
+	start := time.Now()
+	target := portalTarget(c.portal)
 
 	metric := func(desc *prometheus.Desc, v float64, labels ...string) {
 		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...)
 	}
 
-	sessions, total, err := c.client.fetchPortalSessions(c.ctx, c.portal)
-	if err != nil {
-		c.client.log.Errorf("fetching portal data for %s failed with %v", c.portal, err)
-		metric(ctrlUp, 0)
+	snap := c.client.portalSnapshot(c.ctx, c.portal)
+	if c.client.poller != nil {
+		metric(snapshotAge, time.Since(snap.Time).Seconds())
+	}
+	if snap.Err != nil {
+		c.client.log.Errorf("fetching portal data for %s failed with %v", c.portal, snap.Err)
+		c.client.collectScrape(ch, target, portalStages, snap.Err, start)
 		return
 	}
 
-	metric(ctrlUp, 1)
-	metric(portalSessions, float64(total), c.portal)
+	metric(portalSessions, float64(snap.Total), c.portal)
 
-	for _, s := range sessions {
+	for _, s := range snap.Sessions {
 		metric(portalAPSessions, float64(s.Sessions), c.portal, s.DeviceMAC)
 	}
+
+	c.client.collectScrape(ch, target, portalStages, nil, start)
 }
 
 func groupDesc(name, help string) *prometheus.Desc {
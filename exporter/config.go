@@ -1,12 +1,14 @@
 package exporter
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/digineo/cambium-exporter/auth"
@@ -17,16 +19,81 @@ type Client struct {
 	Username string
 	Password string
 	Instance string
+	LoginURL string // optional, overrides the SSO entry point
+
+	// TOTPSecret is the base32-encoded MFA secret (optional).
+	TOTPSecret string
+
+	// LoginSteps optionally names a TOML or JSON file, which replaces the
+	// built-in browser login procedure.
+	LoginSteps string
+
+	// LoginMethod selects how session cookies are obtained. Methods are
+	// registered with auth.Register, the default is a browser login.
+	LoginMethod string
+
+	// ClientID and ClientSecret are credentials for the cnMaestro REST API.
+	// If set, the exporter uses the REST API instead of the web UI's
+	// internal API, and no browser login is performed.
+	ClientID     string
+	ClientSecret string
+
+	// SessionFile optionally names a file where the session cookies are
+	// persisted, so that restarts can skip the browser login.
+	SessionFile string
+
+	// PollInterval enables background polling, if > 0. All AP groups and
+	// portals are then fetched periodically, and scrapes are served from
+	// the last result.
+	PollInterval time.Duration
+
+	// ExportLegacyAPUp enables the deprecated cambium_maestro_ap_up metric,
+	// which has been replaced by cambium_maestro_ap_online and
+	// cambium_maestro_ap_info.
+	ExportLegacyAPUp bool
+
+	// ExportLegacyUp enables the misnamed cambium_maestroup metric, in
+	// addition to cambium_maestro_up.
+	ExportLegacyUp bool
+
+	// Modules define named selections of metric families, which can be
+	// requested from /probe.
+	Modules map[string]Module
+
+	// Aggregate configures the /metrics endpoint.
+	Aggregate Aggregate
 
 	instance *url.URL
 	client   *http.Client
+	auth     auth.Authenticator
+	api      backend
+	rest     *restAPI // nil, unless ClientID is set
 	log      logger
+	poller   *poller // nil, unless PollInterval is set
+	metrics  *selfMetrics
+
+	scrapeErrors scrapeErrors
+
+	ctx        context.Context // cancelled on shutdown, see Start
+	background sync.WaitGroup  // goroutines to wait for on shutdown
+
+	mu               sync.Mutex
+	loginAt          time.Time  // time of the current session's login
+	expires          time.Time  // expiry of the current session, if known
+	generation       uint64     // incremented by setSession
+	pending          *loginCall // in-flight login, see relogin
+	authErr          error      // unrecoverable login failure, see auth.Unrecoverable
+	reloginSuspended time.Time  // no re-login before, see shouldRelogin
+	valid            bool       // whether the controller accepted the session, see ready
+	degraded         error      // reason for degraded operation, see startSession
 }
 
 const (
 	sessionRefreshInterval      = 6 * time.Hour    // how often to refresh session cookie
+	sessionRefreshMargin        = 15 * time.Minute // refresh this long before a known session expiry
 	sessionRefreshRetries       = 24               // number of retries, if session refresh failed (24*30min = 12h)
 	sessionRefershRetryInterval = 30 * time.Minute // interval between failed sesion refresh attempts
+	sessionReloginBackoff       = 30 * time.Minute // suspend re-logins on rejected requests, after one did not help
 )
 
 // LoadClientConfig loads the configuration from a file and initializes
@@ -38,11 +105,21 @@ func LoadClientConfig(file string, verbose bool) (*Client, error) {
 	}
 	defer f.Close()
 
-	c := Client{log: logger(verbose)}
+	c := Client{log: logger(verbose), ctx: context.Background()}
 	if err := toml.NewDecoder(f).Strict(true).Decode(&c); err != nil {
 		return nil, fmt.Errorf("loading config file %q failed: %w", file, err)
 	}
 
+	for name, m := range c.Modules {
+		if err := m.validate(); err != nil {
+			return nil, fmt.Errorf("invalid module %q: %w", name, err)
+		}
+	}
+
+	if err := c.Aggregate.compile(); err != nil {
+		return nil, fmt.Errorf("invalid aggregate config: %w", err)
+	}
+
 	uri, err := url.Parse(c.Instance)
 	if err != nil {
 		return nil, fmt.Errorf("invalid instance url: %w", err)
@@ -55,18 +132,107 @@ func LoadClientConfig(file string, verbose bool) (*Client, error) {
 
 	c.instance = uri
 	c.client = &http.Client{Jar: jar}
+	c.auth, err = auth.New(c.LoginMethod, &auth.Config{
+		Instance:   c.Instance,
+		LoginURL:   c.LoginURL,
+		Username:   c.Username,
+		Password:   c.Password,
+		TOTPSecret: c.TOTPSecret,
+		LoginSteps: c.LoginSteps,
+		Verbose:    verbose,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.metrics = newSelfMetrics(&c)
+	if c.ClientID != "" {
+		c.rest = newRESTAPI(&c)
+		c.api = c.rest
+	} else {
+		c.api = &c
+	}
+	if c.PollInterval > 0 {
+		c.poller = newPoller(&c)
+	}
 	return &c, nil
 }
 
-func (c *Client) login() error {
+// CheckLogin performs a login, and logs the result. In REST API mode,
+// it requests an access token instead.
+func (c *Client) CheckLogin(ctx context.Context) error {
+	if c.rest != nil {
+		if _, err := c.rest.accessToken(ctx); err != nil {
+			return err
+		}
+		c.rest.mu.Lock()
+		expires := c.rest.expires
+		c.rest.mu.Unlock()
+		c.log.Infof("login succeeded: got access token (expires %v)", expires)
+		return nil
+	}
+
+	info, expires, err := c.auth.Authenticate(ctx)
+	if err != nil {
+		return err
+	}
+	c.log.Infof("login succeeded: %+v (expires %v)", info, expires)
+	return nil
+}
+
+// Authenticator returns the authenticator used to obtain session cookies.
+func (c *Client) Authenticator() auth.Authenticator {
+	return c.auth
+}
+
+// SetAuthenticator replaces the authenticator used to obtain session
+// cookies. By default, a browser login with the configured credentials
+// is performed.
+func (c *Client) SetAuthenticator(a auth.Authenticator) {
+	c.auth = a
+}
+
+func (c *Client) login(ctx context.Context) error {
+	c.mu.Lock()
+	authErr := c.authErr
+	c.mu.Unlock()
+	if authErr != nil {
+		return fmt.Errorf("not retrying after previous failure: %w", authErr)
+	}
+
 	c.log.Infof("performing login")
 
-	info, err := auth.Login(c.Username, c.Password, bool(c.log))
+	info, expires, err := c.auth.Authenticate(ctx)
+	c.metrics.loginResult(err)
 	if err != nil {
 		c.log.Errorf("login failed: %v", err)
+		var le *auth.LoginError
+		if errors.As(err, &le) && le.Artifacts != "" {
+			c.log.Infof("login artifacts saved to %s", le.Artifacts)
+		}
+		if auth.Unrecoverable(err) {
+			c.mu.Lock()
+			c.authErr = err
+			c.mu.Unlock()
+		}
 		return err
 	}
+	if info.LoginAt.IsZero() {
+		info.LoginAt = time.Now()
+	}
+
+	c.setSession(info, expires)
+	c.setSessionValid(true)
+	if c.SessionFile != "" {
+		if err := saveSession(c.SessionFile, info, expires); err != nil {
+			c.log.Errorf("persisting session failed: %v", err)
+		}
+	}
+	return nil
+}
 
+// setSession installs the session cookies in the client's cookie jar.
+func (c *Client) setSession(info *auth.AuthInfo, expires time.Time) {
 	xsrfCookie := &http.Cookie{Name: "XSRF-TOKEN"}
 	if info.XSRFToken == "" {
 		xsrfCookie.MaxAge = -1
@@ -82,7 +248,26 @@ func (c *Client) login() error {
 	}
 
 	c.client.Jar.SetCookies(c.instance, []*http.Cookie{sidCookie, xsrfCookie})
-	return nil
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loginAt = info.LoginAt
+	c.expires = expires
+	c.generation++
+}
+
+// nextRefresh returns the delay until the next session refresh. This is
+// usually sessionRefreshInterval after the last login, unless the session
+// is known to expire earlier.
+func (c *Client) nextRefresh() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	next := time.Until(c.loginAt.Add(sessionRefreshInterval))
+	if !c.expires.IsZero() {
+		next = min(next, time.Until(c.expires)-sessionRefreshMargin)
+	}
+	return max(next, sessionRefershRetryInterval/10)
 }
 
 func (c *Client) getCsrfToken() string {
@@ -94,21 +279,56 @@ func (c *Client) getCsrfToken() string {
 	return ""
 }
 
-func (c *Client) startSessionRefresh() {
-	t := time.NewTicker(sessionRefreshInterval)
+// startSession restores or creates the session, and refreshes it
+// periodically, until ctx is cancelled. Repeated failures mark the
+// exporter as degraded (see /-/ready), but are retried nevertheless,
+// unless they can't succeed.
+func (c *Client) startSession(ctx context.Context) {
 	failures := 0
 
-	for range t.C {
-		if err := c.login(); err != nil {
+	if !c.restoreSession(ctx) {
+		if err := c.relogin(ctx, c.sessionGeneration()); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.log.Errorf("initial login failed: %v", err)
+			if auth.Unrecoverable(err) {
+				c.setDegraded(fmt.Errorf("login failed permanently: %w", err))
+				return
+			}
+			failures++
+		}
+	}
+
+	t := time.NewTicker(c.nextRefresh())
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		if err := c.relogin(ctx, c.sessionGeneration()); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
 			c.log.Errorf("session refresh failed: %v", err)
+			if auth.Unrecoverable(err) {
+				c.setDegraded(fmt.Errorf("login failed permanently: %w", err))
+				return
+			}
 			failures++
 			if failures > sessionRefreshRetries {
-				log.Fatal("could not refresh session for 12+ hours, aborting")
+				c.setDegraded(fmt.Errorf("could not refresh session for 12+ hours: %w", err))
 			}
 
 			t.Reset(sessionRefershRetryInterval)
 		} else {
-			t.Reset(sessionRefreshInterval)
+			failures = 0
+			c.setDegraded(nil)
+			t.Reset(c.nextRefresh())
 		}
 	}
 }
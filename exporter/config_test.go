@@ -0,0 +1,28 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextRefresh(t *testing.T) {
+	now := time.Now()
+
+	for _, tc := range []struct {
+		name    string
+		expires time.Time
+		want    time.Duration
+	}{
+		{"unknown expiry", time.Time{}, sessionRefreshInterval},
+		{"late expiry", now.Add(24 * time.Hour), sessionRefreshInterval},
+		{"early expiry", now.Add(time.Hour), time.Hour - sessionRefreshMargin},
+		{"imminent expiry", now.Add(time.Minute), sessionRefershRetryInterval / 10},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Client{loginAt: now, expires: tc.expires}
+			if got := c.nextRefresh(); got > tc.want || got < tc.want-time.Second {
+				t.Errorf("expected next refresh in %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
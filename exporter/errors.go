@@ -0,0 +1,115 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxExcerpt limits the length of APIError.Body.
+const maxExcerpt = 256
+
+// errNotFound is returned, if the controller does not know the requested
+// AP group, but responds with an empty result instead of status 404.
+var errNotFound = errors.New("not found")
+
+// APIError is returned for non-2xx responses from the controller.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string // error message reported by the controller, if any
+	Body       string // truncated response body
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.Body
+	}
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("%s %s: status %d: %s", e.Method, e.Path, e.StatusCode, msg)
+}
+
+// newAPIError constructs an APIError from the response. It does not close
+// the response body.
+func newAPIError(method, path string, res *http.Response) *APIError {
+	body, _ := io.ReadAll(res.Body)
+
+	return &APIError{
+		Method:     method,
+		Path:       path,
+		StatusCode: res.StatusCode,
+		Message:    errorMessage(body),
+		Body:       excerpt(body),
+	}
+}
+
+// errorMessage tries to extract an error message from a JSON body. The
+// controller uses different shapes, depending on the endpoint.
+func errorMessage(body []byte) string {
+	var data struct {
+		Message string          `json:"message"`
+		Error   json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &data) != nil {
+		return ""
+	}
+	if data.Message != "" {
+		return data.Message
+	}
+
+	var s string
+	if json.Unmarshal(data.Error, &s) == nil {
+		return s
+	}
+	var nested struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data.Error, &nested) == nil {
+		return nested.Message
+	}
+	return ""
+}
+
+func excerpt(body []byte) string {
+	s := strings.Join(strings.Fields(string(body)), " ")
+	if len(s) <= maxExcerpt {
+		return s
+	}
+
+	s = s[:maxExcerpt]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1] // cut incomplete rune
+	}
+	return s + "…"
+}
+
+// httpStatus maps an error from one of the fetchers to an HTTP status
+// code for our own response.
+func httpStatus(err error) int {
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound,
+		errors.Is(err, errNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// httpError logs err, and responds with an appropriate status.
+func (c *Client) httpError(w http.ResponseWriter, r *http.Request, err error) {
+	status := httpStatus(err)
+	c.log.Errorf("%s %s: responding with status %d: %v", r.Method, r.URL.Path, status, err)
+	http.Error(w, err.Error(), status)
+}
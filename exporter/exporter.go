@@ -1,6 +1,7 @@
 package exporter
 
 import (
+	"context"
 	_ "embed"
 	"encoding/json"
 	"fmt"
@@ -8,26 +9,75 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func (c *Client) Start(listenAddress, version string) error {
-	if err := c.login(); err != nil {
+// Start runs the web server until ctx is cancelled. It then stops
+// accepting new connections, and waits up to shutdownTimeout for running
+// requests and background tasks (session refresh, polling) to finish.
+func (c *Client) Start(ctx context.Context, listenAddress, version string, shutdownTimeout time.Duration) error {
+	c.ctx = ctx
+	if c.rest == nil {
+		c.background.Go(func() { c.startSession(ctx) })
+	}
+	if c.poller != nil {
+		c.log.Infof("polling all AP groups and portals every %v", c.PollInterval)
+		c.background.Go(func() { c.poller.run(ctx) })
+	}
+
+	var where string
+	if host, port, err := net.SplitHostPort(listenAddress); err == nil && host == "" {
+		where = fmt.Sprintf("http://0.0.0.0:%s/", port)
+	} else {
+		where = fmt.Sprintf("http://%s/", listenAddress)
+	}
+
+	c.log.Infof("Starting exporter on %s", where)
+	srv := &http.Server{Addr: listenAddress, Handler: c.handler(version)}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errc:
 		return err
+	case <-ctx.Done():
+	}
+
+	c.log.Infof("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
 	}
-	go c.startSessionRefresh()
 
+	done := make(chan struct{})
+	go func() {
+		c.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-shutdownCtx.Done():
+		return fmt.Errorf("shutdown: %w", shutdownCtx.Err())
+	}
+}
+
+// handler returns the HTTP handler for all endpoints.
+func (c *Client) handler(version string) http.Handler {
 	router := httprouter.New()
 	router.GET("/", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		apGroups, err := c.fetchAPGroups(r.Context())
+		apGroups, err := c.api.fetchAPGroups(r.Context())
 		if err != nil {
 			log.Printf("fetching AP groups failed: %v", err)
 		}
 
-		portals, err := c.fetchGuestPortals(r.Context())
+		portals, err := c.api.fetchGuestPortals(r.Context())
 		if err != nil {
 			log.Printf("fetching guest portals failed: %v", err)
 		}
@@ -51,22 +101,22 @@ func (c *Client) Start(listenAddress, version string) error {
 	router.GET("/portals/:portal_name/debug", c.portalDebugHandler)
 	router.GET("/portals/:portal_name/metrics", c.portalMetricsHandler)
 
-	var where string
-	if host, port, err := net.SplitHostPort(listenAddress); err == nil && host == "" {
-		where = fmt.Sprintf("http://0.0.0.0:%s/", port)
-	} else {
-		where = fmt.Sprintf("http://%s/", listenAddress)
-	}
+	router.GET("/-/healthy", c.healthyHandler)
+	router.GET("/-/ready", c.readyHandler)
 
-	c.log.Infof("Starting exporter on %s", where)
-	return http.ListenAndServe(listenAddress, router)
+	router.GET("/metrics", c.aggregateMetricsHandler)
+	router.Handler(http.MethodGet, "/metrics/exporter", promhttp.HandlerFor(c.metrics.registry, promhttp.HandlerOpts{}))
+	router.GET("/probe", c.probeHandler)
+	router.GET("/sd", c.sdHandler)
+
+	return router
 }
 
 func (c *Client) listAPGroups(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	r.Body.Close()
-	result, err := c.fetchAPGroups(r.Context())
+	result, err := c.api.fetchAPGroups(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadGateway)
+		c.httpError(w, r, err)
 		return
 	}
 
@@ -91,15 +141,15 @@ func (c *Client) apGroupMetricsHandler(w http.ResponseWriter, r *http.Request, p
 func (c *Client) apGroupDebugHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	apg := params.ByName("ap_group")
 
-	devices, err := c.fetchDevices(r.Context(), apg)
+	devices, err := c.api.fetchDevices(r.Context(), apg)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadGateway)
+		c.httpError(w, r, err)
 		return
 	}
 
-	basic, err := c.fetchAPGroupData(r.Context(), apg)
+	basic, err := c.api.fetchAPGroupData(r.Context(), apg)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadGateway)
+		c.httpError(w, r, err)
 		return
 	}
 
@@ -112,9 +162,9 @@ func (c *Client) apGroupDebugHandler(w http.ResponseWriter, r *http.Request, par
 
 func (c *Client) listPortals(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	r.Body.Close()
-	result, err := c.fetchGuestPortals(r.Context())
+	result, err := c.api.fetchGuestPortals(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadGateway)
+		c.httpError(w, r, err)
 		return
 	}
 
@@ -139,9 +189,9 @@ func (c *Client) portalMetricsHandler(w http.ResponseWriter, r *http.Request, pa
 func (c *Client) portalDebugHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	name := params.ByName("portal_name")
 
-	sessions, total, err := c.fetchPortalSessions(r.Context(), name)
+	sessions, total, err := c.api.fetchPortalSessions(r.Context(), name)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadGateway)
+		c.httpError(w, r, err)
 		return
 	}
 
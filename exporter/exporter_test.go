@@ -0,0 +1,286 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/digineo/cambium-exporter/cnmaestrotest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const apiPrefix = "/0/cn-srv"
+
+// newTestClient returns a client for srv. The config is appended to the
+// generated config file. Logins are performed by srv.Authenticator.
+func newTestClient(t *testing.T, srv *cnmaestrotest.Server, config string) *Client {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "config.toml")
+	data := fmt.Sprintf("Instance = %q\nUsername = %q\nPassword = %q\n%s\n", srv.URL, srv.Username, srv.Password, config)
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadClientConfig(file, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetAuthenticator(srv.Authenticator())
+	return c
+}
+
+func newTestServer(t *testing.T, f cnmaestrotest.Fixtures) *cnmaestrotest.Server {
+	t.Helper()
+	srv := cnmaestrotest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func login(t *testing.T, c *Client) {
+	t.Helper()
+	if err := c.relogin(context.Background(), c.sessionGeneration()); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+}
+
+// gather collects the metrics of a target ("apgroups/NAME" or
+// "portals/NAME"). The result maps `name{label="value",...}` to the value
+// of gauges and counters, and to the sample count of histograms.
+func gather(t *testing.T, c *Client, target string) map[string]float64 {
+	t.Helper()
+
+	coll, err := c.collector(context.Background(), target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(coll)
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]float64)
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			labels := make([]string, 0, len(m.GetLabel()))
+			for _, l := range m.GetLabel() {
+				labels = append(labels, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+			}
+			key := mf.GetName()
+			if len(labels) > 0 {
+				key += "{" + strings.Join(labels, ",") + "}"
+			}
+
+			switch {
+			case m.GetGauge() != nil:
+				values[key] = m.GetGauge().GetValue()
+			case m.GetCounter() != nil:
+				values[key] = m.GetCounter().GetValue()
+			case m.GetHistogram() != nil:
+				values[key] = float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return values
+}
+
+func expectValues(t *testing.T, values map[string]float64, expected map[string]float64) {
+	t.Helper()
+	for key, want := range expected {
+		got, ok := values[key]
+		switch {
+		case !ok:
+			t.Errorf("missing metric %s", key)
+		case got != want:
+			t.Errorf("expected %s = %v, got %v", key, want, got)
+		}
+	}
+}
+
+func TestLogin(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, "")
+	ctx := context.Background()
+
+	if err := c.ready(ctx); err == nil {
+		t.Error("expected client not to be ready before login")
+	}
+
+	login(t, c)
+	if n := srv.Logins(); n != 1 {
+		t.Errorf("expected 1 login, got %d", n)
+	}
+	if err := c.ready(ctx); err != nil {
+		t.Errorf("expected client to be ready, got %v", err)
+	}
+
+	groups, err := c.api.fetchAPGroups(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(groups, ","); got != "Default,Empty" {
+		t.Errorf("unexpected AP groups: %s", got)
+	}
+}
+
+func TestCollectAPGroup(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, "")
+	login(t, c)
+
+	expectValues(t, gather(t, c, "apgroups/Default"), map[string]float64{
+		`cambium_maestro_up`: 1,
+		`cambium_maestro_ap_group_devices_count{name="Default"}`:                                              3,
+		`cambium_maestro_ap_group_devices_offline_count{name="Default"}`:                                      1,
+		`cambium_maestro_ap_online{apgroup="Default",mac="00:04:56:00:00:02"}`:                                0,
+		`cambium_maestro_ap_clients_count{apgroup="Default",mac="00:04:56:00:00:01"}`:                         3,
+		`cambium_maestro_ap_client_rssi_dbm{apgroup="Default",mac="00:04:56:00:00:01"}`:                       3,
+		`cambium_maestro_ap_radio_channel{ap="00:04:56:00:00:03",apgroup="Default",band="6",radio="3"}`:       37,
+		`cambium_maestro_ap_radio_clients_count{ap="00:04:56:00:00:03",apgroup="Default",band="6",radio="3"}`: 1,
+	})
+}
+
+func TestReloginOnExpiredSession(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, "")
+	login(t, c)
+	srv.ExpireSession()
+
+	if _, err := c.api.fetchAPGroups(context.Background()); err != nil {
+		t.Fatalf("expected request to succeed after re-login, got %v", err)
+	}
+	if n := srv.Logins(); n != 2 {
+		t.Errorf("expected 2 logins, got %d", n)
+	}
+	if n := srv.Requests(apiPrefix + "/config/profiles"); n != 2 {
+		t.Errorf("expected the rejected request to be retried once, got %d requests", n)
+	}
+}
+
+func TestReloginRetriesOnce(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, "")
+	login(t, c)
+
+	const path = apiPrefix + "/config/profiles"
+	srv.InjectError(path, http.StatusUnauthorized, -1)
+
+	_, err := c.api.fetchAPGroups(context.Background())
+	if httpStatus(err) != http.StatusBadGateway {
+		t.Errorf("expected the request to fail, got %v", err)
+	}
+	if n := srv.Logins(); n != 2 {
+		t.Errorf("expected 2 logins, got %d", n)
+	}
+	if n := srv.Requests(path); n != 2 {
+		t.Errorf("expected exactly one retry, got %d requests", n)
+	}
+}
+
+func TestGuestPortalPagination(t *testing.T) {
+	var f cnmaestrotest.Fixtures
+	for i := range 250 {
+		f.Portals = append(f.Portals, cnmaestrotest.Portal{Name: fmt.Sprintf("portal-%03d", i)})
+	}
+
+	for _, tc := range []struct {
+		maxPageSize int
+		requests    int
+	}{
+		{0, 3},  // pages of portalsPerPage entries
+		{30, 9}, // controller returns fewer entries than requested
+	} {
+		t.Run(fmt.Sprintf("max page size %d", tc.maxPageSize), func(t *testing.T) {
+			srv := newTestServer(t, f)
+			srv.MaxPageSize = tc.maxPageSize
+			c := newTestClient(t, srv, "")
+			login(t, c)
+
+			names, err := c.api.fetchGuestPortals(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(names) != len(f.Portals) {
+				t.Fatalf("expected %d portals, got %d", len(f.Portals), len(names))
+			}
+			for i, name := range names {
+				if name != f.Portals[i].Name {
+					t.Fatalf("expected portal %d to be %s, got %s", i, f.Portals[i].Name, name)
+				}
+			}
+			if n := srv.Requests(apiPrefix + "/services/guest/portal"); n != tc.requests {
+				t.Errorf("expected %d requests, got %d", tc.requests, n)
+			}
+		})
+	}
+}
+
+func TestScrapeErrors(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, "")
+	login(t, c)
+
+	const (
+		group  = "apgroups/Default"
+		portal = "portals/VisitorPortal"
+	)
+
+	srv.InjectError(apiPrefix+"/stats/profiles/Default/devices", http.StatusInternalServerError, 1)
+	expectValues(t, gather(t, c, group), map[string]float64{
+		`cambium_maestro_up`: 0,
+		`cambium_maestro_scrape_errors_total{stage="group"}`:   0,
+		`cambium_maestro_scrape_errors_total{stage="devices"}`: 1,
+		`cambium_maestro_scrape_errors_total{stage="clients"}`: 0,
+	})
+
+	// failing clients are not fatal
+	srv.InjectError(apiPrefix+"/stats/profiles/Default/clients", http.StatusServiceUnavailable, 1)
+	values := gather(t, c, group)
+	expectValues(t, values, map[string]float64{
+		`cambium_maestro_up`: 1,
+		`cambium_maestro_ap_group_devices_count{name="Default"}`: 3,
+		`cambium_maestro_scrape_errors_total{stage="devices"}`:   1,
+		`cambium_maestro_scrape_errors_total{stage="clients"}`:   1,
+	})
+	if _, ok := values[`cambium_maestro_ap_clients_count{apgroup="Default",mac="00:04:56:00:00:01"}`]; ok {
+		t.Error("expected no client metrics, if fetching clients failed")
+	}
+
+	// unknown names are not counted
+	srv.InjectError(apiPrefix+"/stats/profiles/Default/devices", http.StatusNotFound, 1)
+	expectValues(t, gather(t, c, group), map[string]float64{
+		`cambium_maestro_up`: 0,
+		`cambium_maestro_scrape_errors_total{stage="devices"}`: 1,
+	})
+
+	srv.InjectError(apiPrefix+"/services/guest/session/VisitorPortal", http.StatusBadGateway, 1)
+	expectValues(t, gather(t, c, portal), map[string]float64{
+		`cambium_maestro_up`: 0,
+		`cambium_maestro_scrape_errors_total{stage="sessions"}`: 1,
+	})
+	expectValues(t, gather(t, c, portal), map[string]float64{
+		`cambium_maestro_up`: 1,
+		`cambium_maestro_sessions_count{name="VisitorPortal"}`:  2,
+		`cambium_maestro_scrape_errors_total{stage="sessions"}`: 1,
+	})
+}
+
+func TestLoginMethodConfig(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	file := filepath.Join(t.TempDir(), "config.toml")
+	data := fmt.Sprintf("Instance = %q\nLoginMethod = \"carrier-pigeon\"\n", srv.URL)
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadClientConfig(file, false); err == nil || !strings.Contains(err.Error(), "carrier-pigeon") {
+		t.Errorf("expected error for unknown login method, got %v", err)
+	}
+}
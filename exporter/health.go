@@ -0,0 +1,79 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func (c *Client) setSessionValid(valid bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.valid = valid
+}
+
+func (c *Client) setDegraded(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.degraded = err
+}
+
+// ready returns nil, if the exporter can query the controller, i.e. it
+// holds a session (or access token) accepted by the controller, and is
+// not degraded.
+func (c *Client) ready(ctx context.Context) error {
+	c.mu.Lock()
+	degraded := c.degraded
+	c.mu.Unlock()
+	if degraded != nil {
+		return degraded
+	}
+
+	if c.rest != nil {
+		_, err := c.rest.accessToken(ctx)
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case c.authErr != nil:
+		return c.authErr
+	case !c.valid:
+		return errors.New("no valid session")
+	case !c.expires.IsZero() && time.Now().After(c.expires):
+		return errors.New("session expired")
+	}
+	return nil
+}
+
+// healthyHandler is a liveness check: it always succeeds, as long as the
+// process is able to serve requests. It reports a degraded state in the
+// response body only, since restarting the exporter would just repeat a
+// failed login (and might lock the account). Use /-/ready to detect it.
+func (c *Client) healthyHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	c.mu.Lock()
+	degraded := c.degraded
+	c.mu.Unlock()
+
+	if degraded != nil {
+		fmt.Fprintf(w, "Degraded: %v\n", degraded)
+		return
+	}
+	fmt.Fprintln(w, "Healthy")
+}
+
+// readyHandler responds with 503 Service Unavailable, unless the exporter
+// is ready.
+func (c *Client) readyHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if err := c.ready(r.Context()); err != nil {
+		http.Error(w, "Not ready: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "Ready")
+}
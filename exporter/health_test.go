@@ -0,0 +1,96 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/digineo/cambium-exporter/auth"
+	"github.com/digineo/cambium-exporter/cnmaestrotest"
+)
+
+// probe requests path from c, and returns the status and body.
+func probe(t *testing.T, c *Client, path string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	c.handler("test").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code, rec.Body.String()
+}
+
+func TestHealthBadCredentials(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, "")
+
+	attempts := 0
+	c.SetAuthenticator(auth.AuthenticatorFunc(func(context.Context) (*auth.AuthInfo, time.Time, error) {
+		attempts++
+		return nil, time.Time{}, auth.ErrBadCredentials
+	}))
+	c.startSession(context.Background()) // returns after an unrecoverable failure
+
+	if attempts != 1 {
+		t.Errorf("expected a single login attempt, got %d", attempts)
+	}
+	if code, body := probe(t, c, "/-/healthy"); code != http.StatusOK || !strings.HasPrefix(body, "Degraded: login failed permanently") {
+		t.Errorf("expected /-/healthy to report degraded with status 200, got %d: %s", code, body)
+	}
+	if code, body := probe(t, c, "/-/ready"); code != http.StatusServiceUnavailable || !strings.Contains(body, "bad credentials") {
+		t.Errorf("expected /-/ready to fail with status 503, got %d: %s", code, body)
+	}
+}
+
+func TestHealthDegraded(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, "")
+	login(t, c)
+
+	if code, body := probe(t, c, "/-/ready"); code != http.StatusOK {
+		t.Errorf("expected /-/ready to succeed, got %d: %s", code, body)
+	}
+
+	c.setDegraded(errors.New("could not refresh session"))
+	if code, body := probe(t, c, "/-/healthy"); code != http.StatusOK || !strings.HasPrefix(body, "Degraded") {
+		t.Errorf("expected /-/healthy to report degraded with status 200, got %d: %s", code, body)
+	}
+	if code, body := probe(t, c, "/-/ready"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected /-/ready to fail while degraded, got %d: %s", code, body)
+	}
+
+	c.setDegraded(nil)
+	if code, body := probe(t, c, "/-/healthy"); code != http.StatusOK || body != "Healthy\n" {
+		t.Errorf("expected /-/healthy to report healthy, got %d: %s", code, body)
+	}
+	if code, body := probe(t, c, "/-/ready"); code != http.StatusOK {
+		t.Errorf("expected /-/ready to succeed again, got %d: %s", code, body)
+	}
+}
+
+func TestLoginInvalidCode(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, "")
+
+	attempts := 0
+	valid := srv.Authenticator()
+	c.SetAuthenticator(auth.AuthenticatorFunc(func(ctx context.Context) (*auth.AuthInfo, time.Time, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, time.Time{}, &auth.LoginError{Step: "submitting one-time code", Err: auth.ErrInvalidCode}
+		}
+		return valid.Authenticate(ctx)
+	}))
+
+	if err := c.relogin(context.Background(), c.sessionGeneration()); !errors.Is(err, auth.ErrInvalidCode) {
+		t.Fatalf("expected rejected one-time code, got %v", err)
+	}
+	login(t, c) // not disabled by the previous failure
+	if attempts != 2 {
+		t.Errorf("expected 2 login attempts, got %d", attempts)
+	}
+	if code, body := probe(t, c, "/-/ready"); code != http.StatusOK {
+		t.Errorf("expected /-/ready to succeed, got %d: %s", code, body)
+	}
+}
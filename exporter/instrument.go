@@ -0,0 +1,161 @@
+package exporter
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Counters for bpool usage. Gets minus allocations is the number of
+// reused buffers.
+var (
+	bpoolAllocs atomic.Uint64
+	bpoolGets   atomic.Uint64
+)
+
+// selfMetrics holds the exporter's own metrics, served on
+// /metrics/exporter.
+type selfMetrics struct {
+	registry *prometheus.Registry
+
+	fetchDuration *prometheus.HistogramVec
+	loginAttempts prometheus.Counter
+	loginSuccess  prometheus.Counter
+	loginFailures prometheus.Counter
+	lastLogin     prometheus.Gauge
+}
+
+func newSelfMetrics(c *Client) *selfMetrics {
+	const sub = "exporter"
+	m := &selfMetrics{
+		registry: prometheus.NewRegistry(),
+		fetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: sub,
+			Name:      "fetch_duration_seconds",
+			Help:      "duration of requests to the controller",
+			Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"endpoint", "status"}),
+		loginAttempts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: sub,
+			Name:      "login_attempts_total",
+			Help:      "number of login attempts (or access token requests)",
+		}),
+		loginSuccess: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: sub,
+			Name:      "login_successes_total",
+			Help:      "number of successful logins",
+		}),
+		loginFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: sub,
+			Name:      "login_failures_total",
+			Help:      "number of failed logins",
+		}),
+		lastLogin: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: sub,
+			Name:      "last_login_timestamp_seconds",
+			Help:      "unix timestamp of the last successful login",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.fetchDuration,
+		m.loginAttempts,
+		m.loginSuccess,
+		m.loginFailures,
+		m.lastLogin,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: sub,
+			Name:      "session_age_seconds",
+			Help:      "age of the current session (or access token), 0 if there is none",
+		}, c.sessionAge),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: sub,
+			Name:      "buffer_allocations_total",
+			Help:      "number of response buffers allocated",
+		}, func() float64 { return float64(bpoolAllocs.Load()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: sub,
+			Name:      "buffer_gets_total",
+			Help:      "number of response buffers taken from the pool, including reused ones",
+		}, func() float64 { return float64(bpoolGets.Load()) }),
+	)
+	return m
+}
+
+// observeFetch records the duration of a request. err is the transport
+// error, if any.
+func (m *selfMetrics) observeFetch(path string, res *http.Response, err error, start time.Time) {
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(res.StatusCode)
+	}
+	m.fetchDuration.WithLabelValues(endpointLabel(path), status).Observe(time.Since(start).Seconds())
+}
+
+// loginResult records the outcome of a login attempt.
+func (m *selfMetrics) loginResult(err error) {
+	m.loginAttempts.Inc()
+	if err != nil {
+		m.loginFailures.Inc()
+		return
+	}
+	m.loginSuccess.Inc()
+	m.lastLogin.SetToCurrentTime()
+}
+
+// sessionAge returns the age of the current session in seconds.
+func (c *Client) sessionAge() float64 {
+	var since time.Time
+	if c.rest != nil {
+		c.rest.mu.Lock()
+		since = c.rest.issued
+		c.rest.mu.Unlock()
+	} else {
+		c.mu.Lock()
+		since = c.loginAt
+		c.mu.Unlock()
+	}
+
+	if since.IsZero() {
+		return 0
+	}
+	return time.Since(since).Seconds()
+}
+
+// namedSegments are path prefixes, which are followed by the name of an
+// AP group or portal.
+var namedSegments = []string{
+	"/stats/profiles/",         // cn-srv
+	"/services/guest/session/", // cn-srv
+	"/guest-portals/",          // northbound API
+}
+
+// endpointLabel replaces AP group and portal names in an API path, to
+// limit the label's cardinality.
+func endpointLabel(path string) string {
+	for _, prefix := range namedSegments {
+		if rest, ok := strings.CutPrefix(path, prefix); ok {
+			_, suffix, found := strings.Cut(rest, "/")
+			if found {
+				return prefix + ":name/" + suffix
+			}
+			return prefix + ":name"
+		}
+	}
+	return path
+}
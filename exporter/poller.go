@@ -0,0 +1,244 @@
+package exporter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// groupSnapshot holds the data of an AP group at a point in time. It must
+// not be modified after creation.
+type groupSnapshot struct {
+	Group   *APGroupAPIResponse
+	Devices []*Device
+	Clients []*WirelessClient
+	Time    time.Time // time of fetch
+	Err     error
+	Stage   string // failed stage, if Err != nil
+
+	// ClientsErr is set, if fetching the clients failed. This is not
+	// fatal, the remaining data is still exported.
+	ClientsErr error
+
+	// NoClients is set, if the clients were not fetched, because no
+	// client metrics were requested.
+	NoClients bool
+}
+
+// portalSnapshot holds the sessions of a guest portal at a point in time.
+// It must not be modified after creation.
+type portalSnapshot struct {
+	Sessions []*PortalSession
+	Total    int
+	Time     time.Time // time of fetch
+	Err      error
+	Stage    string // failed stage, if Err != nil
+}
+
+// groupFetcher is implemented by backends, which derive the AP group
+// data from the device list. Both are then fetched at once.
+type groupFetcher interface {
+	fetchAPGroup(ctx context.Context, apGroup string) (*APGroupAPIResponse, []*Device, error)
+}
+
+var _ groupFetcher = (*restAPI)(nil)
+
+// fetchGroupSnapshot fetches an AP group. The clients are only fetched,
+// if withClients is true.
+func (c *Client) fetchGroupSnapshot(ctx context.Context, apGroup string, withClients bool) *groupSnapshot {
+	snap := &groupSnapshot{Stage: stageGroup}
+	if f, ok := c.api.(groupFetcher); ok {
+		snap.Stage = stageDevices
+		snap.Group, snap.Devices, snap.Err = f.fetchAPGroup(ctx, apGroup)
+	} else {
+		snap.Group, snap.Err = c.api.fetchAPGroupData(ctx, apGroup)
+		if snap.Err == nil {
+			snap.Stage = stageDevices
+			snap.Devices, snap.Err = c.api.fetchDevices(ctx, apGroup)
+		}
+	}
+	if snap.Err == nil && withClients {
+		snap.Clients, snap.ClientsErr = c.api.fetchClients(ctx, apGroup)
+	}
+	snap.NoClients = !withClients
+	snap.Time = time.Now()
+
+	if snap.Err != nil {
+		c.scrapeErrors.inc(groupTarget(apGroup), snap.Stage, snap.Err)
+	} else {
+		snap.Stage = ""
+	}
+	if snap.ClientsErr != nil {
+		c.scrapeErrors.inc(groupTarget(apGroup), stageClients, snap.ClientsErr)
+	}
+	return snap
+}
+
+func (c *Client) fetchPortalSnapshot(ctx context.Context, portal string) *portalSnapshot {
+	snap := &portalSnapshot{}
+	snap.Sessions, snap.Total, snap.Err = c.api.fetchPortalSessions(ctx, portal)
+	snap.Time = time.Now()
+
+	if snap.Err != nil {
+		snap.Stage = stageSessions
+		c.scrapeErrors.inc(portalTarget(portal), snap.Stage, snap.Err)
+	}
+	return snap
+}
+
+// poller periodically fetches all AP groups and portals in the
+// background, so that scrapes can be served from memory.
+type poller struct {
+	client   *Client
+	api      backend
+	interval time.Duration
+	log      logger
+
+	mu      sync.RWMutex
+	groups  map[string]*groupSnapshot
+	portals map[string]*portalSnapshot
+}
+
+func newPoller(c *Client) *poller {
+	return &poller{
+		client:   c,
+		api:      c.api,
+		interval: c.PollInterval,
+		log:      c.log,
+		groups:   make(map[string]*groupSnapshot),
+		portals:  make(map[string]*portalSnapshot),
+	}
+}
+
+// run refreshes all snapshots every p.interval, until ctx is cancelled.
+func (p *poller) run(ctx context.Context) {
+	t := time.NewTicker(p.interval)
+	defer t.Stop()
+
+	for {
+		p.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// refresh discovers the AP groups and portals, and fetches each of them.
+// Entries, which no longer exist, are removed.
+func (p *poller) refresh(ctx context.Context) {
+	t0 := time.Now()
+
+	if names, err := p.api.fetchAPGroups(ctx); err != nil {
+		p.log.Errorf("polling: fetching AP groups failed: %v", err)
+	} else {
+		groups := make(map[string]*groupSnapshot, len(names))
+		for _, name := range names {
+			groups[name] = p.keep(p.group(name), p.client.fetchGroupSnapshot(ctx, name, true))
+		}
+		p.mu.Lock()
+		p.groups = groups
+		p.mu.Unlock()
+	}
+
+	if names, err := p.api.fetchGuestPortals(ctx); err != nil {
+		p.log.Errorf("polling: fetching guest portals failed: %v", err)
+	} else {
+		portals := make(map[string]*portalSnapshot, len(names))
+		for _, name := range names {
+			portals[name] = p.keepPortal(p.portal(name), p.client.fetchPortalSnapshot(ctx, name))
+		}
+		p.mu.Lock()
+		p.portals = portals
+		p.mu.Unlock()
+	}
+
+	p.log.Debugf("polling: refreshed all snapshots in %v", time.Since(t0))
+}
+
+// keep returns next, unless it failed and prev is a usable snapshot.
+// Stale data is preferable to no data; its age is exported.
+func (p *poller) keep(prev, next *groupSnapshot) *groupSnapshot {
+	if next.Err != nil {
+		p.log.Errorf("polling: fetching AP group data failed: %v", next.Err)
+		if prev != nil && prev.Err == nil {
+			return prev
+		}
+	}
+	return next
+}
+
+func (p *poller) keepPortal(prev, next *portalSnapshot) *portalSnapshot {
+	if next.Err != nil {
+		p.log.Errorf("polling: fetching portal data failed: %v", next.Err)
+		if prev != nil && prev.Err == nil {
+			return prev
+		}
+	}
+	return next
+}
+
+func (p *poller) group(name string) *groupSnapshot {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.groups[name]
+}
+
+func (p *poller) portal(name string) *portalSnapshot {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.portals[name]
+}
+
+// groupSnapshot returns the current snapshot for an AP group. Unknown
+// groups (e.g. before the first refresh completed) are fetched on demand.
+func (p *poller) groupSnapshot(ctx context.Context, name string) *groupSnapshot {
+	if snap := p.group(name); snap != nil {
+		return snap
+	}
+
+	snap := p.client.fetchGroupSnapshot(ctx, name, true)
+	if snap.Err == nil {
+		p.mu.Lock()
+		p.groups[name] = snap
+		p.mu.Unlock()
+	}
+	return snap
+}
+
+// portalSnapshot returns the current snapshot for a guest portal. Unknown
+// portals are fetched on demand.
+func (p *poller) portalSnapshot(ctx context.Context, name string) *portalSnapshot {
+	if snap := p.portal(name); snap != nil {
+		return snap
+	}
+
+	snap := p.client.fetchPortalSnapshot(ctx, name)
+	if snap.Err == nil {
+		p.mu.Lock()
+		p.portals[name] = snap
+		p.mu.Unlock()
+	}
+	return snap
+}
+
+// groupSnapshot returns a snapshot of an AP group, either from the poller
+// or fetched live. withClients is ignored for the poller's snapshots,
+// which always include the clients.
+func (c *Client) groupSnapshot(ctx context.Context, name string, withClients bool) *groupSnapshot {
+	if c.poller != nil {
+		return c.poller.groupSnapshot(ctx, name)
+	}
+	return c.fetchGroupSnapshot(ctx, name, withClients)
+}
+
+// portalSnapshot returns a snapshot of a guest portal, either from the
+// poller or fetched live.
+func (c *Client) portalSnapshot(ctx context.Context, name string) *portalSnapshot {
+	if c.poller != nil {
+		return c.poller.portalSnapshot(ctx, name)
+	}
+	return c.fetchPortalSnapshot(ctx, name)
+}
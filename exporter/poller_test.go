@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/digineo/cambium-exporter/cnmaestrotest"
+)
+
+func TestPollerUnknownAPGroup(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, `PollInterval = "1h"`)
+	login(t, c)
+	c.poller.refresh(context.Background())
+
+	snap := c.groupSnapshot(context.Background(), "Nope", true)
+	if httpStatus(snap.Err) != http.StatusNotFound || snap.Stage != stageGroup {
+		t.Errorf("expected not found error in stage %s, got %v in stage %s", stageGroup, snap.Err, snap.Stage)
+	}
+
+	values := gather(t, c, "apgroups/Nope")
+	expectValues(t, values, map[string]float64{
+		`cambium_maestro_up`: 0,
+		`cambium_maestro_scrape_errors_total{stage="group"}`: 0,
+	})
+	if c.poller.group("Nope") != nil {
+		t.Error("expected unknown AP group not to be stored")
+	}
+}
+
+func TestPollerRemovedAPGroup(t *testing.T) {
+	f := cnmaestrotest.DemoFixtures()
+	srv := newTestServer(t, f)
+	c := newTestClient(t, srv, `PollInterval = "1h"`)
+	login(t, c)
+	c.poller.refresh(context.Background())
+
+	expectValues(t, gather(t, c, "apgroups/Default"), map[string]float64{
+		`cambium_maestro_up`: 1,
+	})
+
+	f.APGroups = f.APGroups[1:]
+	srv.SetFixtures(f)
+	c.poller.refresh(context.Background())
+
+	if c.poller.group("Default") != nil {
+		t.Error("expected removed AP group to be dropped")
+	}
+	expectValues(t, gather(t, c, "apgroups/Default"), map[string]float64{
+		`cambium_maestro_up`: 0,
+		`cambium_maestro_scrape_errors_total{stage="group"}`: 0,
+	})
+}
@@ -0,0 +1,145 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Module selects the metric families exported by /probe.
+type Module struct {
+	// Families lists metric names, which may contain shell patterns
+	// (e.g. "cambium_maestro_ap_radio_*"). If empty, all metrics are
+	// exported. The exporter-level metrics (see alwaysExported) are
+	// exported regardless.
+	Families []string
+}
+
+// alwaysExported lists the metric families, which are not subject to
+// module selection.
+var alwaysExported = []string{
+	namespace + "_up",
+	namespace + "up",
+	namespace + "_scrape_duration_seconds",
+	namespace + "_scrape_errors_total",
+	namespace + "_snapshot_age_seconds",
+}
+
+// clientFamilies lists the metric families derived from the clients of
+// an AP group. Unless a module selects one of them, the clients are not
+// fetched.
+var clientFamilies = []string{
+	namespace + "_ap_clients_count",
+	namespace + "_ap_ssid_clients_count",
+	namespace + "_ap_client_rssi_dbm",
+	namespace + "_ap_client_snr_db",
+	namespace + "_ap_radio_clients_count",
+}
+
+func (m *Module) validate() error {
+	for _, pattern := range m.Families {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid family pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// selects reports whether the module includes the metric family name.
+func (m *Module) selects(name string) bool {
+	if m == nil || len(m.Families) == 0 {
+		return true
+	}
+	for _, patterns := range [][]string{alwaysExported, m.Families} {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// selectsAny reports whether the module includes any of the metric
+// family names.
+func (m *Module) selectsAny(names []string) bool {
+	for _, name := range names {
+		if m.selects(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// gatherer filters the metric families of g.
+func (m *Module) gatherer(g prometheus.Gatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		mfs, err := g.Gather()
+		result := mfs[:0]
+		for _, mf := range mfs {
+			if m.selects(mf.GetName()) {
+				result = append(result, mf)
+			}
+		}
+		return result, err
+	})
+}
+
+// collector returns the collector for a target ("apgroups/NAME" or
+// "portals/NAME"). The module, if not nil, allows skipping fetches for
+// metrics, which are not selected.
+func (c *Client) collector(ctx context.Context, target string, module *Module) (prometheus.Collector, error) {
+	kind, name, _ := strings.Cut(target, "/")
+	if name == "" {
+		return nil, fmt.Errorf("invalid target %q, expected apgroups/NAME or portals/NAME", target)
+	}
+
+	switch kind {
+	case "apgroups":
+		return &Collector{client: c, apGroup: name, ctx: ctx, module: module}, nil
+	case "portals":
+		return &PortalCollector{client: c, portal: name, ctx: ctx}, nil
+	}
+	return nil, fmt.Errorf("invalid target type %q, expected apgroups or portals", kind)
+}
+
+// probeHandler serves the metrics of a single target, selected by the
+// "target" and "module" query parameters, in the style of the
+// blackbox_exporter.
+func (c *Client) probeHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	q := r.URL.Query()
+
+	var module *Module
+	if name := q.Get("module"); name != "" {
+		m, ok := c.Modules[name]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown module %q", name), http.StatusBadRequest)
+			return
+		}
+		module = &m
+	}
+
+	target := q.Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return
+	}
+	coll, err := c.collector(r.Context(), target, module)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(coll)
+
+	h := promhttp.HandlerFor(module.gatherer(reg), promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+}
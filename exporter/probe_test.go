@@ -0,0 +1,43 @@
+package exporter
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/digineo/cambium-exporter/cnmaestrotest"
+)
+
+func TestProbeModuleFetches(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, `
+[Modules.status]
+Families = ["cambium_maestro_ap_group_*", "cambium_maestro_ap_online"]
+
+[Modules.clients]
+Families = ["cambium_maestro_ap_client_*"]
+`)
+	login(t, c)
+
+	const clientsPath = apiPrefix + "/stats/profiles/Default/clients"
+	for _, tc := range []struct {
+		module   string
+		requests int  // expected number of client list requests
+		clients  bool // whether client metrics are exported
+	}{
+		{"status", 0, false},
+		{"clients", 1, true},
+		{"", 2, true},
+	} {
+		code, body := probe(t, c, "/probe?target=apgroups/Default&module="+tc.module)
+		if code != http.StatusOK || !strings.Contains(body, "cambium_maestro_up 1") {
+			t.Fatalf("module %q: unexpected response %d:\n%s", tc.module, code, body)
+		}
+		if n := srv.Requests(clientsPath); n != tc.requests {
+			t.Errorf("module %q: expected %d client list requests in total, got %d", tc.module, tc.requests, n)
+		}
+		if got := strings.Contains(body, "cambium_maestro_ap_client_rssi_dbm"); got != tc.clients {
+			t.Errorf("module %q: expected client metrics %v, got %v", tc.module, tc.clients, got)
+		}
+	}
+}
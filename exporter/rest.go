@@ -0,0 +1,381 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backend fetches the data exported by the collectors.
+//
+// The Client itself implements the internal cn-srv API, which is used by
+// the web UI and requires a browser login. restAPI implements the
+// official cnMaestro northbound API.
+type backend interface {
+	fetchAPGroups(ctx context.Context) ([]string, error)
+	fetchAPGroupData(ctx context.Context, apGroup string) (*APGroupAPIResponse, error)
+	fetchDevices(ctx context.Context, apGroup string) ([]*Device, error)
+	fetchClients(ctx context.Context, apGroup string) ([]*WirelessClient, error)
+	fetchGuestPortals(ctx context.Context) ([]string, error)
+	fetchPortalSessions(ctx context.Context, name string) ([]*PortalSession, int, error)
+}
+
+var (
+	_ backend = (*Client)(nil)
+	_ backend = (*restAPI)(nil)
+)
+
+const (
+	restPrefix      = "/api/v2"
+	restPageSize    = 100
+	restTokenMargin = time.Minute      // renew access tokens this long before they expire
+	restTimeout     = 30 * time.Second // timeout for REST API requests, including token requests
+)
+
+// restAPI talks to the cnMaestro northbound API (/api/v2), using OAuth2
+// client credentials for authentication.
+type restAPI struct {
+	instance     *url.URL
+	clientID     string
+	clientSecret string
+	client       *http.Client
+	log          logger
+	metrics      *selfMetrics
+
+	mu      sync.Mutex
+	token   string
+	issued  time.Time
+	expires time.Time
+	pending *tokenCall
+}
+
+// tokenCall is an in-flight token request, shared by concurrent callers
+// of accessToken (like loginCall for browser logins).
+type tokenCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+func newRESTAPI(c *Client) *restAPI {
+	return &restAPI{
+		instance:     c.instance,
+		clientID:     c.ClientID,
+		clientSecret: c.ClientSecret,
+		client:       &http.Client{Timeout: restTimeout},
+		log:          c.log,
+		metrics:      c.metrics,
+	}
+}
+
+// accessToken returns a cached access token, or requests a new one.
+// Concurrent callers share a single request, which is not aborted when
+// ctx is cancelled (but is limited by restTimeout). a.mu is not held
+// during the request.
+func (a *restAPI) accessToken(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	if a.token != "" && time.Now().Before(a.expires) {
+		token := a.token
+		a.mu.Unlock()
+		return token, nil
+	}
+	call := a.pending
+	if call == nil {
+		call = &tokenCall{done: make(chan struct{})}
+		a.pending = call
+		go func() {
+			call.token, call.err = a.requestToken(context.WithoutCancel(ctx))
+			a.metrics.loginResult(call.err)
+			a.mu.Lock()
+			a.pending = nil
+			a.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	a.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// requestToken requests a new access token, and stores it.
+func (a *restAPI) requestToken(ctx context.Context) (string, error) {
+	u2 := *a.instance // dup
+	u2.Path = restPrefix + "/access/token"
+	u2.RawQuery = ""
+	body := strings.NewReader(url.Values{"grant_type": {"client_credentials"}}.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u2.String(), body)
+	if err != nil {
+		return "", fmt.Errorf("failed to construct HTTP request: %w", err)
+	}
+	req.SetBasicAuth(url.QueryEscape(a.clientID), url.QueryEscape(a.clientSecret))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request access token: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", newAPIError(http.MethodPost, u2.Path, res)
+	}
+
+	var data struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"` // seconds
+	}
+	if err = json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return "", fmt.Errorf("failed to decode access token response: %w", err)
+	}
+	if data.AccessToken == "" {
+		return "", fmt.Errorf("no access token in response")
+	}
+
+	a.log.Debugf("got access token, expires in %ds", data.ExpiresIn)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = data.AccessToken
+	a.issued = time.Now()
+	a.expires = a.issued.Add(time.Duration(data.ExpiresIn)*time.Second - restTokenMargin)
+	return a.token, nil
+}
+
+// invalidateToken discards token, unless it was already replaced.
+func (a *restAPI) invalidateToken(token string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token == token {
+		a.token = ""
+	}
+}
+
+// fetch performs an authenticated GET request, and decodes the response
+// into v. If the token is rejected, a new one is requested, and the
+// request is retried once.
+func (a *restAPI) fetch(ctx context.Context, path string, params url.Values, v interface{}) error {
+	for attempt := 0; ; attempt++ {
+		token, err := a.accessToken(ctx)
+		if err != nil {
+			return err
+		}
+
+		retry, err := a.do(ctx, token, path, params, v)
+		if !retry || attempt > 0 {
+			return err
+		}
+		a.log.Infof("access token rejected, requesting a new one")
+		a.invalidateToken(token)
+	}
+}
+
+func (a *restAPI) do(ctx context.Context, token, path string, params url.Values, v interface{}) (retry bool, err error) {
+	u2 := *a.instance // dup
+	u2.Path = restPrefix + path
+	u2.RawQuery = params.Encode()
+	url := u2.String()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to construct HTTP request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+
+	t0 := time.Now()
+	res, err := a.client.Do(req)
+	a.metrics.observeFetch(path, res, err, t0)
+	if err != nil {
+		a.log.Infof("error fetching %s: %v", url, err)
+		return false, err
+	}
+	defer res.Body.Close()
+	a.log.Debugf("fetch %s (status %d) in %v", url, res.StatusCode, time.Since(t0))
+
+	if res.StatusCode == http.StatusUnauthorized {
+		return true, newAPIError(http.MethodGet, path, res)
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return false, newAPIError(http.MethodGet, path, res)
+	}
+	if err = json.NewDecoder(res.Body).Decode(v); err != nil {
+		return false, fmt.Errorf("failed to decode response from %s: %w", path, err)
+	}
+	return false, nil
+}
+
+// restList fetches all pages of a list endpoint.
+func restList[T any](ctx context.Context, a *restAPI, path string, params url.Values) ([]T, error) {
+	var result []T
+
+	for offset := 0; ; {
+		q := url.Values{}
+		for k, v := range params {
+			q[k] = v
+		}
+		q.Set("limit", strconv.Itoa(restPageSize))
+		q.Set("offset", strconv.Itoa(offset))
+
+		var page struct {
+			Data   []T `json:"data"`
+			Paging struct {
+				Limit  int `json:"limit"`
+				Offset int `json:"offset"`
+				Total  int `json:"total"`
+			} `json:"paging"`
+		}
+		if err := a.fetch(ctx, path, q, &page); err != nil {
+			return nil, err
+		}
+
+		result = append(result, page.Data...)
+		offset += len(page.Data)
+		if len(page.Data) == 0 || offset >= page.Paging.Total {
+			return result, nil
+		}
+	}
+}
+
+func (a *restAPI) fetchAPGroups(ctx context.Context) ([]string, error) {
+	data, err := restList[struct {
+		Name string `json:"name"`
+	}](ctx, a, "/ap-groups", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch AP group list: %w", err)
+	}
+
+	groups := make([]string, 0, len(data))
+	for _, g := range data {
+		groups = append(groups, g.Name)
+	}
+	return groups, nil
+}
+
+// fetchAPGroup fetches the devices of an AP group, and aggregates the AP
+// group status from them. The northbound API does not report the number
+// of clients in the past 24 hours, nor the config sync state.
+func (a *restAPI) fetchAPGroup(ctx context.Context, apGroup string) (*APGroupAPIResponse, []*Device, error) {
+	data, err := restList[restDeviceResponse](ctx, a, "/devices/statistics", url.Values{
+		"type":     {"wifi-enterprise"},
+		"ap_group": {apGroup},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch devices for AP group %q: %w", apGroup, err)
+	}
+	if len(data) == 0 {
+		// unknown AP groups have no devices either
+		if err = a.checkAPGroup(ctx, apGroup); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	apg := APGroupAPIResponse{
+		Name:         apGroup,
+		DevicesCount: len(data),
+	}
+	devs := make([]*Device, 0, len(data))
+	for i := range data {
+		dev := data[i].Normalize()
+		if !dev.Online {
+			apg.DevicesOffline++
+		}
+		apg.ClientCount += data[i].ConnectedClients
+		devs = append(devs, dev)
+	}
+	return &apg, devs, nil
+}
+
+// checkAPGroup returns an error wrapping errNotFound, if the AP group
+// does not exist.
+func (a *restAPI) checkAPGroup(ctx context.Context, apGroup string) error {
+	groups, err := a.fetchAPGroups(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(groups, apGroup) {
+		return fmt.Errorf("AP group %q: %w", apGroup, errNotFound)
+	}
+	return nil
+}
+
+func (a *restAPI) fetchAPGroupData(ctx context.Context, apGroup string) (*APGroupAPIResponse, error) {
+	apg, _, err := a.fetchAPGroup(ctx, apGroup)
+	return apg, err
+}
+
+func (a *restAPI) fetchDevices(ctx context.Context, apGroup string) ([]*Device, error) {
+	_, devs, err := a.fetchAPGroup(ctx, apGroup)
+	return devs, err
+}
+
+// fetchClients returns the wireless clients of an AP group. The northbound
+// API does not report the radio index, only the band.
+func (a *restAPI) fetchClients(ctx context.Context, apGroup string) ([]*WirelessClient, error) {
+	data, err := restList[restClientResponse](ctx, a, "/devices/clients", url.Values{
+		"type":     {"wifi-enterprise"},
+		"ap_group": {apGroup},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch clients for AP group %q: %w", apGroup, err)
+	}
+
+	clients := make([]*WirelessClient, 0, len(data))
+	for i := range data {
+		clients = append(clients, data[i].Normalize())
+	}
+	return clients, nil
+}
+
+func (a *restAPI) fetchGuestPortals(ctx context.Context) ([]string, error) {
+	data, err := restList[PortalAPIResponse](ctx, a, "/guest-portals", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch guest portal list: %w", err)
+	}
+
+	names := make([]string, 0, len(data))
+	for _, portal := range data {
+		names = append(names, portal.Name)
+	}
+	return names, nil
+}
+
+func (a *restAPI) fetchPortalSessions(ctx context.Context, name string) ([]*PortalSession, int, error) {
+	data, err := restList[struct {
+		DeviceMAC string `json:"ap_mac"`
+	}](ctx, a, "/guest-portals/"+url.PathEscape(name)+"/sessions", nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch portal session data for portal %s: %w", name, err)
+	}
+
+	count := make(map[string]int) // key = AP MAC address
+	for _, s := range data {
+		count[s.DeviceMAC]++
+	}
+
+	sessions := make([]*PortalSession, 0, len(count))
+	for mac, n := range count {
+		sessions = append(sessions, &PortalSession{
+			DeviceMAC:  mac,
+			Sessions:   n,
+			PortalName: name,
+		})
+	}
+	return sessions, len(data), nil
+}
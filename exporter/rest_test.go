@@ -0,0 +1,171 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/digineo/cambium-exporter/cnmaestrotest"
+)
+
+const restPath = "/api/v2"
+
+func newRESTTestClient(t *testing.T, srv *cnmaestrotest.Server, secret string) *Client {
+	t.Helper()
+	return newTestClient(t, srv, fmt.Sprintf("ClientID = %q\nClientSecret = %q", srv.ClientID, secret))
+}
+
+func TestRESTCollect(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newRESTTestClient(t, srv, srv.ClientSecret)
+
+	expectValues(t, gather(t, c, "apgroups/Default"), map[string]float64{
+		`cambium_maestro_up`: 1,
+		`cambium_maestro_ap_group_devices_count{name="Default"}`:                                              3,
+		`cambium_maestro_ap_group_devices_offline_count{name="Default"}`:                                      1,
+		`cambium_maestro_ap_group_client_count{name="Default"}`:                                               5,
+		`cambium_maestro_ap_online{apgroup="Default",mac="00:04:56:00:00:01"}`:                                1,
+		`cambium_maestro_ap_clients_count{apgroup="Default",mac="00:04:56:00:00:03"}`:                         2,
+		`cambium_maestro_ap_radio_noise_floor{ap="00:04:56:00:00:03",apgroup="Default",band="5",radio="2"}`:   -95,
+		`cambium_maestro_ap_radio_clients_count{ap="00:04:56:00:00:03",apgroup="Default",band="6",radio="3"}`: 1,
+	})
+	if n := srv.Requests(restPath + "/devices/statistics"); n != 1 {
+		t.Errorf("expected the device list to be fetched once per scrape, got %d requests", n)
+	}
+
+	expectValues(t, gather(t, c, "portals/VisitorPortal"), map[string]float64{
+		`cambium_maestro_up`: 1,
+		`cambium_maestro_sessions_count{name="VisitorPortal"}`:                              2,
+		`cambium_maestro_ap_sessions_count{mac="00:04:56:00:00:01",portal="VisitorPortal"}`: 2,
+	})
+
+	if n := srv.Requests(restPath + "/access/token"); n != 1 {
+		t.Errorf("expected the access token to be reused, got %d token requests", n)
+	}
+}
+
+func TestRESTTokenRenewal(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newRESTTestClient(t, srv, srv.ClientSecret)
+	ctx := context.Background()
+
+	if _, err := c.api.fetchAPGroups(ctx); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpireToken()
+	groups, err := c.api.fetchAPGroups(ctx)
+	if err != nil {
+		t.Fatalf("expected request to succeed with a new token, got %v", err)
+	}
+	if len(groups) != 2 {
+		t.Errorf("expected 2 AP groups, got %v", groups)
+	}
+	if n := srv.Requests(restPath + "/access/token"); n != 2 {
+		t.Errorf("expected 2 token requests, got %d", n)
+	}
+	if n := srv.Requests(restPath + "/ap-groups"); n != 3 {
+		t.Errorf("expected the rejected request to be retried once, got %d requests", n)
+	}
+}
+
+func TestRESTBadCredentials(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newRESTTestClient(t, srv, "wrong")
+
+	err := c.CheckLogin(context.Background())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 error, got %v", err)
+	}
+	if err := c.ready(context.Background()); err == nil {
+		t.Error("expected client not to be ready")
+	}
+}
+
+func TestRESTUnknownAPGroup(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, fmt.Sprintf("ClientID = %q\nClientSecret = %q\nPollInterval = \"1h\"", srv.ClientID, srv.ClientSecret))
+
+	snap := c.groupSnapshot(context.Background(), "DoesNotExist", true)
+	if httpStatus(snap.Err) != http.StatusNotFound {
+		t.Errorf("expected not found error, got %v", snap.Err)
+	}
+	expectValues(t, gather(t, c, "apgroups/DoesNotExist"), map[string]float64{
+		`cambium_maestro_up`: 0,
+		`cambium_maestro_scrape_errors_total{stage="devices"}`: 0,
+	})
+	if c.poller.group("DoesNotExist") != nil {
+		t.Error("expected unknown AP group not to be stored")
+	}
+
+	// existing AP groups without devices are fine
+	expectValues(t, gather(t, c, "apgroups/Empty"), map[string]float64{
+		`cambium_maestro_up`: 1,
+		`cambium_maestro_ap_group_devices_count{name="Empty"}`: 0,
+	})
+}
+
+// blockingTransport holds back access token requests until release is
+// closed.
+type blockingTransport struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == restPath+"/access/token" {
+		b.started <- struct{}{}
+		<-b.release
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRESTTokenRequestShared(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newRESTTestClient(t, srv, srv.ClientSecret)
+	tr := &blockingTransport{started: make(chan struct{}, 1), release: make(chan struct{})}
+	c.rest.client.Transport = tr
+
+	const callers = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for range callers {
+		wg.Go(func() {
+			if _, err := c.rest.accessToken(context.Background()); err != nil {
+				errs <- err
+			}
+		})
+	}
+	<-tr.started
+
+	// a pending token request must not block other users of the client
+	done := make(chan struct{})
+	go func() {
+		c.sessionAge()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := c.ready(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected readiness check to time out, got %v", err)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocked by pending token request")
+	}
+
+	close(tr.release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("token request failed: %v", err)
+	}
+	if n := srv.Requests(restPath + "/access/token"); n != 1 {
+		t.Errorf("expected a single token request, got %d", n)
+	}
+}
@@ -0,0 +1,78 @@
+package exporter
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Stages of a scrape, used as "stage" label for scrapeErrorsTotal.
+const (
+	stageGroup    = "group"    // fetching AP group data (see also groupFetcher)
+	stageDevices  = "devices"  // fetching the AP group's devices
+	stageClients  = "clients"  // fetching the AP group's clients
+	stageSessions = "sessions" // fetching portal sessions
+)
+
+var (
+	groupStages  = []string{stageGroup, stageDevices, stageClients}
+	portalStages = []string{stageSessions}
+)
+
+func groupTarget(name string) string  { return "apgroups/" + name }
+func portalTarget(name string) string { return "portals/" + name }
+
+// scrapeErrors counts failed fetches per target and stage. The zero value
+// is ready to use.
+type scrapeErrors struct {
+	mu     sync.Mutex
+	counts map[string]map[string]uint64 // target => stage => count
+}
+
+// inc increments the error counter for the given target and stage. Errors
+// for unknown targets are not counted, so that requests with arbitrary
+// names can't grow the map.
+func (s *scrapeErrors) inc(target, stage string, err error) {
+	if httpStatus(err) == http.StatusNotFound {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.counts == nil {
+		s.counts = make(map[string]map[string]uint64)
+	}
+	if s.counts[target] == nil {
+		s.counts[target] = make(map[string]uint64)
+	}
+	s.counts[target][stage]++
+}
+
+func (s *scrapeErrors) get(target, stage string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[target][stage]
+}
+
+// collectScrape emits the exporter-level metrics of a scrape. err is the
+// error of the scrape (if any), and start its start time.
+func (c *Client) collectScrape(ch chan<- prometheus.Metric, target string, stages []string, err error, start time.Time) {
+	up := 1.0
+	if err != nil {
+		up = 0
+	}
+	ch <- prometheus.MustNewConstMetric(ctrlUp, prometheus.GaugeValue, up)
+	if c.ExportLegacyUp {
+		ch <- prometheus.MustNewConstMetric(ctrlUpLegacy, prometheus.GaugeValue, up)
+	}
+
+	for _, stage := range stages {
+		n := c.scrapeErrors.get(target, stage)
+		ch <- prometheus.MustNewConstMetric(scrapeErrorsTotal, prometheus.CounterValue, float64(n), stage)
+	}
+
+	ch <- prometheus.MustNewConstMetric(scrapeDuration, prometheus.GaugeValue, time.Since(start).Seconds())
+}
@@ -0,0 +1,110 @@
+package exporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// sdTargetGroup is an entry of the Prometheus HTTP service discovery
+// response, see https://prometheus.io/docs/prometheus/latest/http_sd/.
+type sdTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+const sdMetaPrefix = "__meta_cambium_maestro_"
+
+// sdHandler lists all AP groups and portals for Prometheus' http_sd_config.
+// Each target points back to this exporter, with the metrics path of the
+// AP group or portal, or to /probe, if a "module" parameter is given (see
+// sdSetPath).
+func (c *Client) sdHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	module := r.URL.Query().Get("module")
+	if _, ok := c.Modules[module]; module != "" && !ok {
+		http.Error(w, "unknown module "+strconv.Quote(module), http.StatusBadRequest)
+		return
+	}
+
+	apGroups, err := c.api.fetchAPGroups(r.Context())
+	if err != nil {
+		c.httpError(w, r, err)
+		return
+	}
+	portals, err := c.api.fetchGuestPortals(r.Context())
+	if err != nil {
+		c.httpError(w, r, err)
+		return
+	}
+
+	// point Prometheus to the address it used to reach us
+	address := r.Host
+
+	groups := make([]sdTargetGroup, 0, len(apGroups)+len(portals))
+	for _, name := range apGroups {
+		labels := c.sdGroupLabels(name)
+		labels["apgroup"] = name
+		sdSetPath(labels, groupTarget(name), module)
+		groups = append(groups, sdTargetGroup{Targets: []string{address}, Labels: labels})
+	}
+	for _, name := range portals {
+		labels := map[string]string{"portal": name}
+		sdSetPath(labels, portalTarget(name), module)
+		groups = append(groups, sdTargetGroup{Targets: []string{address}, Labels: labels})
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(groups)
+}
+
+// sdSetPath sets the metrics path (and parameters) for a target.
+// Prometheus escapes __metrics_path__ when building the scrape URL, so
+// the name is not escaped here. Names which can't be used as a single
+// path segment are scraped via /probe instead.
+func sdSetPath(labels map[string]string, target, module string) {
+	_, name, _ := strings.Cut(target, "/")
+	if module == "" && !strings.Contains(name, "/") && name != "." && name != ".." {
+		labels["__metrics_path__"] = "/" + target + "/metrics"
+		return
+	}
+	labels["__metrics_path__"] = "/probe"
+	labels["__param_target"] = target
+	if module != "" {
+		labels["__param_module"] = module
+	}
+}
+
+// sdGroupLabels returns metadata for an AP group, taken from its poller
+// snapshot. Without polling (or before the first refresh), no metadata
+// is available, since fetching every AP group would make discovery as
+// expensive as scraping.
+func (c *Client) sdGroupLabels(name string) map[string]string {
+	labels := make(map[string]string)
+	if c.poller == nil {
+		return labels
+	}
+
+	snap := c.poller.group(name)
+	if snap == nil || snap.Err != nil {
+		return labels
+	}
+	labels[sdMetaPrefix+"devices_count"] = strconv.Itoa(snap.Group.DevicesCount)
+	if site, ok := commonSite(snap.Devices); ok {
+		labels["site"] = site
+	}
+	return labels
+}
+
+// commonSite returns the site name shared by all devices.
+func commonSite(devices []*Device) (site string, ok bool) {
+	for i, dev := range devices {
+		if i > 0 && dev.SiteName != site {
+			return "", false
+		}
+		site = dev.SiteName
+	}
+	return site, site != ""
+}
@@ -0,0 +1,109 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/digineo/cambium-exporter/cnmaestrotest"
+)
+
+// discover returns the labels of the targets from /sd, keyed by the
+// apgroup or portal label.
+func discover(t *testing.T, c *Client, query string) map[string]map[string]string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	c.sdHandler(rec, httptest.NewRequest(http.MethodGet, "/sd"+query, nil), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var groups []sdTargetGroup
+	if err := json.NewDecoder(rec.Body).Decode(&groups); err != nil {
+		t.Fatal(err)
+	}
+	targets := make(map[string]map[string]string, len(groups))
+	for _, g := range groups {
+		targets[g.Labels["apgroup"]+g.Labels["portal"]] = g.Labels
+	}
+	return targets
+}
+
+func TestServiceDiscovery(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, "")
+	login(t, c)
+
+	targets := discover(t, c, "")
+	if len(targets) != 3 {
+		t.Fatalf("expected 3 targets, got %v", targets)
+	}
+	if path := targets["VisitorPortal"]["__metrics_path__"]; path != "/portals/VisitorPortal/metrics" {
+		t.Errorf("unexpected metrics path %q", path)
+	}
+	for _, label := range []string{"site", sdMetaPrefix + "devices_count"} {
+		if v, ok := targets["Default"][label]; ok {
+			t.Errorf("expected no %s label without polling, got %q", label, v)
+		}
+	}
+	if n := srv.Requests(apiPrefix + "/stats/profiles/Default/devices"); n != 0 {
+		t.Errorf("expected no AP group to be fetched, got %d requests", n)
+	}
+}
+
+func TestServiceDiscoveryPolling(t *testing.T) {
+	srv := newTestServer(t, cnmaestrotest.DemoFixtures())
+	c := newTestClient(t, srv, `PollInterval = "1h"`)
+	login(t, c)
+
+	if _, ok := discover(t, c, "")["Default"][sdMetaPrefix+"devices_count"]; ok {
+		t.Error("expected no device count before the first refresh")
+	}
+
+	c.poller.refresh(context.Background())
+	labels := discover(t, c, "")["Default"]
+	if v := labels[sdMetaPrefix+"devices_count"]; v != "3" {
+		t.Errorf("expected device count 3, got %q", v)
+	}
+	if v := labels["site"]; v != "Headquarters" {
+		t.Errorf("expected site Headquarters, got %q", v)
+	}
+}
+
+func TestServiceDiscoveryPath(t *testing.T) {
+	f := cnmaestrotest.DemoFixtures()
+	f.APGroups[0].Name = "Head Office"
+	f.APGroups[1].Name = "North/South"
+	srv := newTestServer(t, f)
+	c := newTestClient(t, srv, "")
+	login(t, c)
+
+	targets := discover(t, c, "")
+
+	// Prometheus builds the scrape URL from __metrics_path__ like this
+	// (and escapes it).
+	labels := targets["Head Office"]
+	u := url.URL{Path: labels["__metrics_path__"]}
+	if got := u.String(); got != "/apgroups/Head%20Office/metrics" {
+		t.Errorf("unexpected scrape URL %q", got)
+	}
+
+	rec := httptest.NewRecorder()
+	c.handler("test").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, u.String(), nil))
+	if !strings.Contains(rec.Body.String(), `cambium_maestro_ap_group_devices_count{name="Head Office"} 3`) {
+		t.Errorf("expected metrics of Head Office, got status %d:\n%s", rec.Code, rec.Body)
+	}
+
+	labels = targets["North/South"]
+	if path, target := labels["__metrics_path__"], labels["__param_target"]; path != "/probe" || target != "apgroups/North/South" {
+		t.Errorf("expected North/South to be scraped via /probe, got %q (target %q)", path, target)
+	}
+	if module, ok := labels["__param_module"]; ok {
+		t.Errorf("expected no module, got %q", module)
+	}
+}
@@ -0,0 +1,176 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/digineo/cambium-exporter/auth"
+)
+
+// sessionState is the on-disk representation of a session.
+type sessionState struct {
+	SessionID string    `json:"sid"`
+	XSRFToken string    `json:"xsrf_token,omitempty"`
+	LoginAt   time.Time `json:"login_at"`
+	Expires   time.Time `json:"expires,omitzero"`
+}
+
+// saveSession atomically replaces the session state file. The file is
+// only readable by the owner, as it contains credentials.
+func saveSession(file string, info *auth.AuthInfo, expires time.Time) error {
+	data, err := json.Marshal(&sessionState{
+		SessionID: info.SessionID,
+		XSRFToken: info.XSRFToken,
+		LoginAt:   info.LoginAt,
+		Expires:   expires,
+	})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create session file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after successful rename
+
+	if err = tmp.Chmod(0o600); err == nil {
+		_, err = tmp.Write(data)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
+
+// loadSession reads the session state file. It returns an error wrapping
+// fs.ErrNotExist, if the file does not exist.
+func loadSession(file string) (*auth.AuthInfo, time.Time, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var state sessionState
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to decode session file %q: %w", file, err)
+	}
+	if state.SessionID == "" {
+		return nil, time.Time{}, fmt.Errorf("session file %q contains no session", file)
+	}
+
+	info := &auth.AuthInfo{
+		SessionID: state.SessionID,
+		XSRFToken: state.XSRFToken,
+		LoginAt:   state.LoginAt,
+	}
+	return info, state.Expires, nil
+}
+
+// restoreSession loads the session from c.SessionFile and checks whether
+// the controller still accepts it. It returns false, if a fresh login is
+// required.
+func (c *Client) restoreSession(ctx context.Context) bool {
+	if c.SessionFile == "" {
+		return false
+	}
+
+	info, expires, err := loadSession(c.SessionFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			c.log.Errorf("loading session failed: %v", err)
+		}
+		return false
+	}
+	if !expires.IsZero() && time.Now().After(expires) {
+		c.log.Infof("persisted session has expired")
+		return false
+	}
+
+	c.setSession(info, expires)
+	if err = c.validateSession(ctx); err != nil {
+		c.log.Infof("persisted session is invalid: %v", err)
+		return false
+	}
+	c.setSessionValid(true)
+
+	c.log.Infof("restored session from %s (logged in at %v)", c.SessionFile, info.LoginAt)
+	return true
+}
+
+// validateSession asks the controller for the current user.
+func (c *Client) validateSession(ctx context.Context) error {
+	res, err := c.do(ctx, http.MethodGet, "/user/me", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	if mt, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mt != "application/json" {
+		return fmt.Errorf("unexpected content type %q", mt)
+	}
+	return nil
+}
+
+// loginCall is an in-flight login, shared by concurrent callers of
+// relogin.
+type loginCall struct {
+	done chan struct{}
+	err  error
+}
+
+// sessionGeneration returns a counter, which is incremented whenever new
+// session cookies are installed.
+func (c *Client) sessionGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// relogin performs a login, unless the session was replaced after gen was
+// obtained from sessionGeneration. Concurrent callers share a single login,
+// which is only aborted on shutdown, not when ctx is cancelled.
+func (c *Client) relogin(ctx context.Context, gen uint64) error {
+	c.mu.Lock()
+	if c.generation != gen {
+		c.mu.Unlock()
+		return nil
+	}
+	call := c.pending
+	if call == nil {
+		call = &loginCall{done: make(chan struct{})}
+		c.pending = call
+		c.background.Go(func() {
+			call.err = c.login(c.ctx)
+			c.mu.Lock()
+			c.pending = nil
+			c.mu.Unlock()
+			close(call.done)
+		})
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"runtime/debug"
+	"syscall"
 	"time"
 
 	"github.com/digineo/cambium-exporter/auth"
@@ -28,9 +32,12 @@ func main() {
 	log.SetFlags(log.Lshortfile)
 
 	listenAddress := kingpin.Flag("web.listen-address", "Address on which to expose metrics and web interface.").Default(":9836").String()
+	shutdownTimeout := kingpin.Flag("web.shutdown-timeout", "Time to wait for running requests and logins to finish on shutdown.").Default("30s").Duration()
 	configFile := kingpin.Flag("config", "Path to configuration file.").Default(DefaultConfigPath).String()
 	performLogin := kingpin.Flag("login", "Perform login test, and dump session cookie.").Bool()
 	loginTimeout := kingpin.Flag("login.timeout", "Timeout for login and session refresh.").Default("5m").Short('t').Duration()
+	remoteURL := kingpin.Flag("chrome.remote-url", "DevTools URL of a running Chrome instance to use for logins, instead of starting a new one.").Envar("CHROME_REMOTE_URL").String()
+	artifactsDir := kingpin.Flag("login.artifacts-dir", "Directory to save screenshots and page dumps of failed logins into.").String()
 	verbose := kingpin.Flag("verbose", "Increase log verbosity.").Short('V').Bool()
 	versionFlag := kingpin.Flag("version", "Print version information and exit.").Short('v').Bool()
 	kingpin.HelpFlag.Short('h')
@@ -47,26 +54,43 @@ func main() {
 	if binary := os.Getenv("CHROME_BINARY"); binary != "" {
 		auth.SetExecPath(binary)
 	}
+	if *remoteURL != "" {
+		auth.SetRemoteURL(*remoteURL)
+	}
 	if *loginTimeout > time.Second {
 		auth.SetLoginTimeout(*loginTimeout)
 	}
+	if *artifactsDir != "" {
+		auth.SetArtifactsDir(*artifactsDir)
+	}
 
 	client, err := exporter.LoadClientConfig(*configFile, *verbose)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// restore default behaviour, a second signal terminates immediately
+		<-ctx.Done()
+		stop()
+	}()
+
 	if *performLogin {
-		info, err := auth.Login(client.Username, client.Password, *verbose)
-		if err != nil {
+		if err := client.CheckLogin(ctx); err != nil {
+			var le *auth.LoginError
+			if errors.As(err, &le) && le.Artifacts != "" {
+				log.Printf("login artifacts saved to %s", le.Artifacts)
+			}
 			log.Fatalf("login failed: %v", err)
-			return
 		}
-		log.Printf("login succeeded: %+v", info)
 		return
 	}
 
-	log.Fatal(client.Start(*listenAddress, Version()))
+	if err := client.Start(ctx, *listenAddress, Version(), *shutdownTimeout); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func printVersion() {